@@ -47,9 +47,9 @@ func (v *Vector) Copy() *Vector {
 // Bytes returns the bit vector as an array of bytes in LSB order. The
 // returned array is a copy of the underlying data.
 func (v *Vector) Bytes() []byte {
-	out := make([]byte, v.bits/8)
+	out := make([]byte, (v.bits+7)/8)
 	for i := 0; i < len(out); i++ {
-		out[i] = byte(v.data[i/4] >> (uint(i) % 4 * 8))
+		out[i] = byte(v.data[i/8] >> (uint(i) % 8 * 8))
 	}
 	return out
 }
@@ -236,3 +236,21 @@ func popcount(x uint64) (n int) {
 	x *= 0x0101010101010101
 	return int(x >> 56)
 }
+
+// Compare orders vectors of the same length by their value as
+// unsigned integers with bit 0 least significant, returning -1, 0 or
+// +1
+func (v *Vector) Compare(rhs *Vector) int {
+	if v.Len() != rhs.Len() {
+		panic("Compare(): len mismatch")
+	}
+	for i := len(v.data) - 1; i >= 0; i-- {
+		switch {
+		case v.data[i] < rhs.data[i]:
+			return -1
+		case v.data[i] > rhs.data[i]:
+			return 1
+		}
+	}
+	return 0
+}
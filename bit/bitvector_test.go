@@ -99,3 +99,30 @@ func TestUnaligned(t *testing.T) {
 		t.Errorf("rsh into undefined bits lived: %x", a.data[len(a.data)-1])
 	}
 }
+
+func TestCompare(t *testing.T) {
+	a := NewVector(130)
+	b := NewVector(130)
+	if a.Compare(b) != 0 {
+		t.Error("zero vectors differ")
+	}
+	a.Set(3)
+	b.Set(129)
+	if a.Compare(b) != -1 || b.Compare(a) != 1 {
+		t.Errorf("Compare: %d %d", a.Compare(b), b.Compare(a))
+	}
+	a.Set(129)
+	if a.Compare(b) != 1 {
+		t.Errorf("Compare: %d", a.Compare(b))
+	}
+}
+
+func TestBytes(t *testing.T) {
+	v := NewVector(130)
+	v.Set(0).Set(9).Set(71).Set(129)
+	want := make([]byte, 17)
+	want[0], want[1], want[8], want[16] = 0x01, 0x02, 0x80, 0x02
+	if got := v.Bytes(); string(got) != string(want) {
+		t.Errorf("Bytes()=%x, want %x", got, want)
+	}
+}
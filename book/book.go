@@ -0,0 +1,264 @@
+// Package book implements an opening book: statistics about the moves
+// played from each position early in a collection of games. Positions
+// are canonicalized over the symmetries of the board, so the book
+// treats openings that differ only in orientation as the same.
+package book
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+// DefaultMoves is the default number of moves recorded from each game
+const DefaultMoves = 30
+
+// Book records the moves played from positions in the opening
+type Book struct {
+	// Moves is the number of moves recorded from each game
+	Moves int
+	// Games is the number of games added to the book
+	Games     int
+	positions map[uint64][]*entry
+}
+
+// entry holds the statistics for a move in the canonical orientation
+// of a position
+type entry struct {
+	x, y                int
+	count, wins, losses int
+}
+
+// New returns an empty book that records the first moves of each game
+func New(moves int) *Book {
+	return &Book{Moves: moves, positions: make(map[uint64][]*entry)}
+}
+
+// Candidate is a move suggested by the book
+type Candidate struct {
+	// Move is the point to play. A pass is -1,-1.
+	Move  sgf.Point
+	Count int
+	// Wins and Losses count the games won and lost by the player
+	// who made the move
+	Wins, Losses int
+}
+
+// WinRate returns the fraction of decided games won by the player who
+// made the move
+func (c *Candidate) WinRate() float64 {
+	if c.Wins+c.Losses == 0 {
+		return 0
+	}
+	return float64(c.Wins) / float64(c.Wins+c.Losses)
+}
+
+// key identifies a position, which should be in canonical form
+func key(p *game.Position) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 3+p.Width*p.Height)
+	buf = append(buf, byte(p.Width), byte(p.Height))
+	if p.ToPlay == game.White {
+		buf = append(buf, 'W')
+	} else {
+		buf = append(buf, 'B')
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			c, ok := p.At(x, y)
+			switch {
+			case !ok:
+				buf = append(buf, '.')
+			case c == game.Black:
+				buf = append(buf, 'X')
+			default:
+				buf = append(buf, 'O')
+			}
+		}
+	}
+	h.Write(buf)
+	return h.Sum64()
+}
+
+// canonicalMove maps a move in p to the canonical form of p. If the
+// canonical form is symmetric, the move could map to several points;
+// the first in row-major order is chosen, so that equivalent moves
+// are counted together.
+func canonicalMove(c *game.Position, s game.Symmetry, x, y int) (int, int) {
+	x, y = s.Point(c.Width, c.Height, x, y)
+	if x < 0 {
+		return x, y
+	}
+	for _, u := range game.Symmetries[1:] {
+		if u.Square() && c.Width != c.Height {
+			continue
+		}
+		if !c.Transform(u).Equal(c) {
+			continue
+		}
+		if ux, uy := u.Point(c.Width, c.Height, x, y); uy < y || uy == y && ux < x {
+			x, y = ux, uy
+		}
+	}
+	return x, y
+}
+
+// Add records the opening of the main line of a game tree
+func (b *Book) Add(t *sgf.GameTree) error {
+	if len(t.Principal.Nodes) == 0 {
+		return errors.New("book: empty game tree")
+	}
+	root := &t.Principal.Nodes[0]
+	w, h, err := root.BoardSize()
+	if err != nil {
+		return err
+	}
+	var winner game.Color
+	decided := false
+	if re, ok := root.Value("RE"); ok && len(re) > 1 && re[1] == '+' {
+		winner, decided = re[0] == 'W', re[0] == 'B' || re[0] == 'W'
+	}
+
+	g := game.NewRect(w, h)
+	type played struct {
+		key   uint64
+		x, y  int
+		color game.Color
+	}
+	var moves []played
+	for tree := t; tree != nil && len(moves) < b.Moves; {
+		for i := range tree.Principal.Nodes {
+			n := &tree.Principal.Nodes[i]
+			before := g.Position()
+			if err := g.PlayNode(n); err != nil {
+				return err
+			}
+			if n.Get("B") == nil && n.Get("W") == nil {
+				continue
+			}
+			x, y, color, _ := g.LastMove()
+			// A move out of turn changes the player to play;
+			// record the position as the mover saw it
+			before.ToPlay = color
+			c, s := before.Canonical()
+			x, y = canonicalMove(c, s, x, y)
+			moves = append(moves, played{key(c), x, y, color})
+			if len(moves) == b.Moves {
+				break
+			}
+		}
+		if len(tree.Children) == 0 {
+			break
+		}
+		tree = tree.Children[0]
+	}
+
+	b.Games++
+	for _, m := range moves {
+		e := b.entry(m.key, m.x, m.y)
+		e.count++
+		if decided {
+			if winner == m.color {
+				e.wins++
+			} else {
+				e.losses++
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Book) entry(k uint64, x, y int) *entry {
+	for _, e := range b.positions[k] {
+		if e.x == x && e.y == y {
+			return e
+		}
+	}
+	e := &entry{x: x, y: y}
+	b.positions[k] = append(b.positions[k], e)
+	return e
+}
+
+// AddFile records the openings of every game in an SGF file
+func (b *Book) AddFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c, err := sgf.ParseSGF(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for i, t := range c.Trees {
+		if err := b.Add(t); err != nil {
+			return fmt.Errorf("%s: game %d: %v", path, i+1, err)
+		}
+	}
+	return nil
+}
+
+// AddDir records the openings of every SGF file under a directory,
+// returning the errors for any files that could not be read
+func (b *Book) AddDir(dir string) ([]error, error) {
+	var errs []error
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.ToLower(filepath.Ext(path)) != ".sgf" {
+			return err
+		}
+		if err := b.AddFile(path); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	return errs, err
+}
+
+// Prune removes moves played fewer than min times
+func (b *Book) Prune(min int) {
+	for k, es := range b.positions {
+		kept := es[:0]
+		for _, e := range es {
+			if e.count >= min {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(b.positions, k)
+		} else {
+			b.positions[k] = kept
+		}
+	}
+}
+
+// Lookup returns the moves recorded for the current position of g, in
+// g's orientation, most popular first
+func (b *Book) Lookup(g *game.Game) []Candidate {
+	c, s := g.Position().Canonical()
+	inv := s.Inverse()
+	var out []Candidate
+	for _, e := range b.positions[key(c)] {
+		x, y := inv.Point(c.Width, c.Height, e.x, e.y)
+		out = append(out, Candidate{
+			Move:   sgf.Point{X: x, Y: y},
+			Count:  e.count,
+			Wins:   e.wins,
+			Losses: e.losses,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Move.Y < out[j].Move.Y ||
+			out[i].Move.Y == out[j].Move.Y && out[i].Move.X < out[j].Move.X
+	})
+	return out
+}
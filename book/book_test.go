@@ -0,0 +1,113 @@
+package book
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+func play(t *testing.T, moves ...string) *game.Game {
+	g := game.New(9)
+	for _, m := range moves {
+		x, y, err := sgf.PropValue(m).Point()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Move(x, y); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	return g
+}
+
+func candidates(cs []Candidate) string {
+	var out []string
+	for _, c := range cs {
+		out = append(out, fmt.Sprintf("%s:%d/%d/%d",
+			sgf.PointValue(c.Move.X, c.Move.Y), c.Count, c.Wins, c.Losses))
+	}
+	return strings.Join(out, " ")
+}
+
+func testBook(t *testing.T, moves int) *Book {
+	b := New(moves)
+	errs, err := b.AddDir("testdata/games")
+	if err != nil || errs != nil {
+		t.Fatal(err, errs)
+	}
+	return b
+}
+
+func TestLookup(t *testing.T) {
+	b := testBook(t, 2)
+	if b.Games != 4 {
+		t.Errorf("games=%d", b.Games)
+	}
+	cases := []struct {
+		moves []string
+		want  string
+	}{
+		{nil, "ee:3/2/1 cc:1/0/0"},
+		// Every white reply to tengen is a corner 3-3 point, so
+		// they are counted together
+		{[]string{"ee"}, "cc:3/1/2"},
+		{[]string{"ee", "gg"}, ""},
+		// Moves beyond the limit are not recorded
+		{[]string{"ee", "cc"}, ""},
+	}
+	for _, tc := range cases {
+		if got := candidates(b.Lookup(play(t, tc.moves...))); got != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.moves, got, tc.want)
+		}
+	}
+}
+
+func TestLookupOrientation(t *testing.T) {
+	b := testBook(t, 4)
+	// The games reached ee cc in three orientations. In this one,
+	// the reply dd to ee cg becomes df, which is reported as fd
+	// since the position is symmetric about the diagonal.
+	if got, want := candidates(b.Lookup(play(t, "ee", "cc"))), "gc:2/1/1 fd:1/1/0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The same position rotated a half turn
+	if got, want := candidates(b.Lookup(play(t, "ee", "gg"))), "cg:2/1/1 df:1/1/0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	b := testBook(t, 2)
+	b.Prune(2)
+	if got := candidates(b.Lookup(play(t))); got != "ee:3/2/1" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	b := testBook(t, 4)
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Moves != 4 || loaded.Games != 4 {
+		t.Errorf("moves=%d games=%d", loaded.Moves, loaded.Games)
+	}
+	for _, moves := range [][]string{nil, {"ee"}, {"ee", "cc"}} {
+		g := play(t, moves...)
+		if got, want := candidates(loaded.Lookup(g)), candidates(b.Lookup(g)); got != want {
+			t.Errorf("%v: got %q, want %q", moves, got, want)
+		}
+	}
+	if _, err := Load("testdata/games/a.sgf"); err == nil {
+		t.Error("loaded an SGF file as a book")
+	}
+}
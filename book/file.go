@@ -0,0 +1,144 @@
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// A book file starts with a magic number and a header of unsigned
+// varints: the format version, Moves, Games and the number of
+// positions. Each position follows as its 64-bit little-endian key
+// and the number of moves, and each move as signed varints x and y
+// and unsigned varints count, wins and losses. Positions are sorted
+// by key so that files are reproducible.
+const (
+	magic         = "MGBOOK"
+	formatVersion = 1
+)
+
+// ErrBadFormat is returned when reading a file that is not a book
+var ErrBadFormat = errors.New("book: not an opening book")
+
+// Save writes the book to a file, replacing it atomically
+func (b *Book) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = b.write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (b *Book) write(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	var buf [binary.MaxVarintLen64]byte
+	uvarint := func(v uint64) {
+		w.Write(buf[:binary.PutUvarint(buf[:], v)])
+	}
+	varint := func(v int64) {
+		w.Write(buf[:binary.PutVarint(buf[:], v)])
+	}
+
+	w.WriteString(magic)
+	uvarint(formatVersion)
+	uvarint(uint64(b.Moves))
+	uvarint(uint64(b.Games))
+	uvarint(uint64(len(b.positions)))
+	keys := make([]uint64, 0, len(b.positions))
+	for k := range b.positions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		binary.LittleEndian.PutUint64(buf[:8], k)
+		w.Write(buf[:8])
+		es := b.positions[k]
+		uvarint(uint64(len(es)))
+		for _, e := range es {
+			varint(int64(e.x))
+			varint(int64(e.y))
+			uvarint(uint64(e.count))
+			uvarint(uint64(e.wins))
+			uvarint(uint64(e.losses))
+		}
+	}
+	return w.Flush()
+}
+
+// Load reads a book written by Save
+func Load(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := read(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return b, nil
+}
+
+func read(r *bufio.Reader) (*Book, error) {
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, head); err != nil || string(head) != magic {
+		return nil, ErrBadFormat
+	}
+	var err error
+	uvarint := func() int {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(r)
+		return int(v)
+	}
+	varint := func() int {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(r)
+		return int(v)
+	}
+
+	if v := uvarint(); err == nil && v != formatVersion {
+		return nil, fmt.Errorf("book: unsupported version %d", v)
+	}
+	b := New(uvarint())
+	b.Games = uvarint()
+	n := uvarint()
+	var key [8]byte
+	for i := 0; i < n && err == nil; i++ {
+		if _, err = io.ReadFull(r, key[:]); err != nil {
+			break
+		}
+		k := binary.LittleEndian.Uint64(key[:])
+		m := uvarint()
+		for j := 0; j < m && err == nil; j++ {
+			e := &entry{x: varint(), y: varint()}
+			e.count, e.wins, e.losses = uvarint(), uvarint(), uvarint()
+			b.positions[k] = append(b.positions[k], e)
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
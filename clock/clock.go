@@ -0,0 +1,333 @@
+// Package clock implements game clocks under the common time
+// controls: absolute time, Fischer increments, Japanese byo-yomi and
+// Canadian overtime. Clocks do not read the time themselves; every
+// operation takes the time at which it happens, so that callers can
+// supply wall time or, in tests, a fake.
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"nelhage.com/minigo/game"
+)
+
+// Kind is a system of time control
+type Kind int
+
+const (
+	// Absolute gives each player a fixed amount of time for the
+	// whole game
+	Absolute Kind = iota
+	// Fischer adds an increment to a player's time after each
+	// move
+	Fischer
+	// ByoYomi follows main time with a number of periods. A move
+	// made within a period does not use it up; each period that
+	// runs out does.
+	ByoYomi
+	// Canadian follows main time with periods in which a number
+	// of stones must be played
+	Canadian
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Absolute:
+		return "absolute"
+	case Fischer:
+		return "fischer"
+	case ByoYomi:
+		return "byoyomi"
+	case Canadian:
+		return "canadian"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Control is a time control
+type Control struct {
+	Kind Kind
+	// Main is each player's main time
+	Main time.Duration
+	// Increment is added after each move under Fischer timing
+	Increment time.Duration
+	// Period is the length of a byo-yomi period, or the time to
+	// play Stones stones in under Canadian overtime
+	Period time.Duration
+	// Periods is the number of byo-yomi periods
+	Periods int
+	// Stones is the number of stones to play in each Canadian
+	// period
+	Stones int
+}
+
+// Parse parses a time control written as "absolute:MAIN",
+// "fischer:MAIN+INCREMENT", "byoyomi:MAIN+PERIODSxPERIOD" or
+// "canadian:MAIN+STONES/PERIOD", where times are in the format of
+// time.ParseDuration; for example, "byoyomi:10m+5x30s".
+func Parse(spec string) (*Control, error) {
+	kind, rest := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		kind, rest = spec[:i], spec[i+1:]
+	}
+	main, over := rest, ""
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		main, over = rest[:i], rest[i+1:]
+	}
+	c := &Control{}
+	var err error
+	if c.Main, err = time.ParseDuration(main); err != nil {
+		return nil, fmt.Errorf("time control %q: %v", spec, err)
+	}
+	// count parses an overtime of the form "<n><sep><duration>"
+	count := func(sep string) (int, time.Duration, error) {
+		parts := strings.SplitN(over, sep, 2)
+		if len(parts) != 2 {
+			return 0, 0, fmt.Errorf("expected N%sDURATION after +", sep)
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		d, err := time.ParseDuration(parts[1])
+		return n, d, err
+	}
+	switch kind {
+	case "absolute":
+		c.Kind = Absolute
+		if over != "" {
+			err = errors.New("absolute time has no overtime")
+		}
+	case "fischer":
+		c.Kind = Fischer
+		c.Increment, err = time.ParseDuration(over)
+	case "byoyomi":
+		c.Kind = ByoYomi
+		c.Periods, c.Period, err = count("x")
+	case "canadian":
+		c.Kind = Canadian
+		c.Stones, c.Period, err = count("/")
+	default:
+		err = fmt.Errorf("unknown kind %q", kind)
+	}
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("time control %q: %v", spec, err)
+	}
+	return c, nil
+}
+
+// Validate checks that a time control makes sense
+func (c *Control) Validate() error {
+	switch {
+	case c.Main < 0 || c.Increment < 0 || c.Period < 0:
+		return errors.New("negative time")
+	case c.Kind == ByoYomi && (c.Period == 0 || c.Periods <= 0):
+		return errors.New("byo-yomi needs at least one period of some length")
+	case c.Kind == Canadian && (c.Period == 0 || c.Stones <= 0):
+		return errors.New("Canadian overtime needs periods of some length and at least one stone")
+	case c.Kind != ByoYomi && c.Kind != Canadian && c.Main == 0:
+		return errors.New("no main time")
+	}
+	return nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// TimeLimit returns the main time in seconds, as in SGF's TM property
+func (c *Control) TimeLimit() float64 {
+	return c.Main.Seconds()
+}
+
+// Overtime describes the overtime, as in SGF's OT property, which has
+// no standard format. It is empty for absolute time.
+func (c *Control) Overtime() string {
+	switch c.Kind {
+	case Fischer:
+		return fmt.Sprintf("%s Fischer", seconds(c.Increment))
+	case ByoYomi:
+		return fmt.Sprintf("%dx%s byo-yomi", c.Periods, seconds(c.Period))
+	case Canadian:
+		return fmt.Sprintf("%d/%s Canadian", c.Stones, seconds(c.Period))
+	default:
+		return ""
+	}
+}
+
+// State is one player's clock
+type State struct {
+	// Main is the main time left
+	Main time.Duration
+	// Overtime is true once main time has run out
+	Overtime bool
+	// Period is the time left in the current overtime period
+	Period time.Duration
+	// Periods is the number of byo-yomi periods left
+	Periods int
+	// Stones is the number of stones left to play in the current
+	// Canadian period
+	Stones int
+	// Flagged is true if the player has run out of time
+	Flagged bool
+}
+
+// TimeLeft returns a player's clock as it is recorded in SGF
+func (c *Control) TimeLeft(s State) game.TimeLeft {
+	if !s.Overtime {
+		return game.TimeLeft{Seconds: s.Main.Seconds()}
+	}
+	t := game.TimeLeft{Seconds: s.Period.Seconds()}
+	if c.Kind == ByoYomi {
+		t.Periods = s.Periods
+	} else {
+		t.Periods = s.Stones
+	}
+	return t
+}
+
+// spend charges d to a player's clock, returning false if they run out
+// of time. Time running backwards is not charged.
+func (c *Control) spend(s *State, d time.Duration) bool {
+	if s.Flagged {
+		return false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if !s.Overtime {
+		if d < s.Main {
+			s.Main -= d
+			return true
+		}
+		d -= s.Main
+		s.Main = 0
+		if c.Kind != ByoYomi && c.Kind != Canadian {
+			s.Flagged = true
+			return false
+		}
+		s.Overtime = true
+		s.Period, s.Periods, s.Stones = c.Period, c.Periods, c.Stones
+	}
+	if c.Kind == ByoYomi {
+		for d >= s.Period {
+			d -= s.Period
+			s.Periods--
+			s.Period = c.Period
+			if s.Periods <= 0 {
+				s.Periods, s.Period, s.Flagged = 0, 0, true
+				return false
+			}
+		}
+	} else if d >= s.Period {
+		s.Period, s.Flagged = 0, true
+		return false
+	}
+	s.Period -= d
+	return true
+}
+
+// moved updates a player's clock after they complete a move in time
+func (c *Control) moved(s *State) {
+	switch {
+	case c.Kind == Fischer:
+		s.Main += c.Increment
+	case c.Kind == ByoYomi && s.Overtime:
+		s.Period = c.Period
+	case c.Kind == Canadian && s.Overtime:
+		s.Stones--
+		if s.Stones == 0 {
+			s.Period, s.Stones = c.Period, c.Stones
+		}
+	}
+}
+
+// Clock is the pair of clocks for a game. It holds no references, so
+// copying a Clock copies its state.
+type Clock struct {
+	Control Control
+
+	black, white State
+	// running is true while turn's clock is running, as it has
+	// been since since
+	running bool
+	turn    game.Color
+	since   time.Time
+}
+
+// New returns a clock for a game under time control c. No clock runs
+// until the first move.
+func New(c Control) *Clock {
+	s := State{Main: c.Main}
+	return &Clock{Control: c, black: s, white: s}
+}
+
+func (c *Clock) state(p game.Color) *State {
+	if p == game.White {
+		return &c.white
+	}
+	return &c.black
+}
+
+// Move records that p moved at time at, stopping p's clock and
+// starting the opponent's. It returns false if p had run out of time
+// by then, in which case no clock is left running. A move made while
+// p's clock is not running is not charged.
+func (c *Clock) Move(p game.Color, at time.Time) bool {
+	if c.running && c.turn == p {
+		s := c.state(p)
+		if !c.Control.spend(s, at.Sub(c.since)) {
+			c.running = false
+			return false
+		}
+		c.Control.moved(s)
+	}
+	c.running, c.turn, c.since = true, !p, at
+	return true
+}
+
+// Stop stops the running clock at time at, charging its player for the
+// time used
+func (c *Clock) Stop(at time.Time) {
+	if c.running {
+		c.Control.spend(c.state(c.turn), at.Sub(c.since))
+		c.running = false
+	}
+}
+
+// Start stops the running clock, as Stop does, and starts p's
+func (c *Clock) Start(p game.Color, at time.Time) {
+	c.Stop(at)
+	c.running, c.turn, c.since = true, p, at
+}
+
+// Running returns the player whose clock is running, if any
+func (c *Clock) Running() (game.Color, bool) {
+	return c.turn, c.running
+}
+
+// State returns p's clock as it stands at time at
+func (c *Clock) State(p game.Color, at time.Time) State {
+	s := *c.state(p)
+	if c.running && c.turn == p {
+		c.Control.spend(&s, at.Sub(c.since))
+	}
+	return s
+}
+
+// Flagged returns the player whose clock is running if they have run
+// out of time by at
+func (c *Clock) Flagged(at time.Time) (game.Color, bool) {
+	if !c.running {
+		return game.Black, false
+	}
+	return c.turn, c.State(c.turn, at).Flagged
+}
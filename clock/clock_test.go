@@ -0,0 +1,151 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"nelhage.com/minigo/game"
+)
+
+var t0 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(d time.Duration) time.Time {
+	return t0.Add(d)
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		spec string
+		want Control
+		ot   string
+	}{
+		{"absolute:10m", Control{Kind: Absolute, Main: 10 * time.Minute}, ""},
+		{"fischer:5m+10s", Control{Kind: Fischer, Main: 5 * time.Minute, Increment: 10 * time.Second}, "10 Fischer"},
+		{"byoyomi:10m+5x30s", Control{Kind: ByoYomi, Main: 10 * time.Minute, Periods: 5, Period: 30 * time.Second}, "5x30 byo-yomi"},
+		{"canadian:0s+25/5m", Control{Kind: Canadian, Stones: 25, Period: 5 * time.Minute}, "25/300 Canadian"},
+	}
+	for _, tc := range cases {
+		c, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.spec, err)
+			continue
+		}
+		if *c != tc.want || c.Overtime() != tc.ot {
+			t.Errorf("Parse(%q) = %+v, %q", tc.spec, *c, c.Overtime())
+		}
+	}
+	for _, bad := range []string{"10m", "absolute:10m+5s", "byoyomi:10m+5/30s", "byoyomi:10m+0x30s", "fischer:0s+5s", "sudden:1m"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestAbsolute(t *testing.T) {
+	c := New(Control{Kind: Absolute, Main: time.Minute})
+	// Black's first move starts white's clock without charging
+	// black
+	c.Move(game.Black, at(time.Hour))
+	if !c.Move(game.White, at(time.Hour+40*time.Second)) {
+		t.Fatal("white flagged early")
+	}
+	if s := c.State(game.White, at(0)); s.Main != 20*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	if p, ok := c.Flagged(at(time.Hour + 99*time.Second)); ok {
+		t.Errorf("flagged %v", p)
+	}
+	if s := c.State(game.Black, at(time.Hour+70*time.Second)); s.Main != 30*time.Second || s.Flagged {
+		t.Errorf("black's running clock: %+v", s)
+	}
+	if p, ok := c.Flagged(at(time.Hour + 100*time.Second)); !ok || p != game.Black {
+		t.Errorf("Flagged() = %v, %v", p, ok)
+	}
+	if c.Move(game.Black, at(2*time.Hour)) {
+		t.Error("black moved after running out of time")
+	}
+}
+
+func TestFischer(t *testing.T) {
+	c := New(Control{Kind: Fischer, Main: time.Minute, Increment: 10 * time.Second})
+	c.Move(game.Black, at(0))
+	c.Move(game.White, at(30*time.Second))
+	c.Move(game.Black, at(30*time.Second))
+	if s := c.State(game.White, at(30*time.Second)); s.Main != 40*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	if !c.Move(game.White, at(69*time.Second)) {
+		t.Fatal("white flagged")
+	}
+	if s := c.State(game.White, at(69*time.Second)); s.Main != 11*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+}
+
+func TestByoYomi(t *testing.T) {
+	ctrl := Control{Kind: ByoYomi, Main: time.Minute, Periods: 3, Period: 10 * time.Second}
+	c := New(ctrl)
+	c.Move(game.Black, at(0))
+	// White runs out of main time and uses 5s of the first period,
+	// which is restored by moving
+	if !c.Move(game.White, at(65*time.Second)) {
+		t.Fatal("white flagged")
+	}
+	s := c.State(game.White, at(0))
+	if !s.Overtime || s.Periods != 3 || s.Period != 10*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	if tl := ctrl.TimeLeft(s); tl != (game.TimeLeft{Seconds: 10, Periods: 3}) {
+		t.Errorf("TimeLeft: %+v", tl)
+	}
+	c.Move(game.Black, at(65*time.Second))
+	// Using up two whole periods leaves one
+	if !c.Move(game.White, at(90*time.Second)) {
+		t.Fatal("white flagged")
+	}
+	if s := c.State(game.White, at(0)); s.Periods != 1 {
+		t.Errorf("white: %+v", s)
+	}
+	c.Move(game.Black, at(90*time.Second))
+	if _, ok := c.Flagged(at(99 * time.Second)); ok {
+		t.Error("flagged in the last period")
+	}
+	if _, ok := c.Flagged(at(100 * time.Second)); !ok {
+		t.Error("not flagged after the last period")
+	}
+}
+
+func TestCanadian(t *testing.T) {
+	c := New(Control{Kind: Canadian, Stones: 2, Period: 10 * time.Second})
+	c.Move(game.Black, at(0))
+	c.Move(game.White, at(4*time.Second))
+	c.Move(game.Black, at(4*time.Second))
+	if s := c.State(game.White, at(4*time.Second)); !s.Overtime || s.Stones != 1 || s.Period != 6*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	// The second stone completes the period, which starts afresh
+	c.Move(game.White, at(9*time.Second))
+	c.Move(game.Black, at(9*time.Second))
+	if s := c.State(game.White, at(9*time.Second)); s.Stones != 2 || s.Period != 10*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	if c.Move(game.White, at(19*time.Second)) {
+		t.Error("white moved after the period ran out")
+	}
+}
+
+func TestStop(t *testing.T) {
+	c := New(Control{Kind: Absolute, Main: time.Minute})
+	c.Move(game.Black, at(0))
+	c.Stop(at(20 * time.Second))
+	if _, ok := c.Running(); ok {
+		t.Error("running after Stop")
+	}
+	if s := c.State(game.White, at(time.Hour)); s.Main != 40*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+	c.Start(game.White, at(time.Hour))
+	if s := c.State(game.White, at(time.Hour+10*time.Second)); s.Main != 30*time.Second {
+		t.Errorf("white: %+v", s)
+	}
+}
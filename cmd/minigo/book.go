@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"nelhage.com/minigo/book"
+)
+
+var bookCommands = map[string]func(args []string) error{
+	"build": bookBuild,
+}
+
+func bookMain(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: minigo book <build> [args]")
+	}
+	cmd, ok := bookCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd(args[1:])
+}
+
+func bookBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	out := fs.String("o", "book.bin", "output file")
+	moves := fs.Int("moves", book.DefaultMoves, "number of moves to record from each game")
+	min := fs.Int("min", 1, "drop moves played in fewer games than this")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("usage: minigo book build [flags] DIR...")
+	}
+
+	b := book.New(*moves)
+	for _, dir := range fs.Args() {
+		errs, err := b.AddDir(dir)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	b.Prune(*min)
+	if err := b.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d games\n", *out, b.Games)
+	return nil
+}
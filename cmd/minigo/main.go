@@ -2,18 +2,84 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 
+	"nelhage.com/minigo/clock"
 	"nelhage.com/minigo/web"
 )
 
+// commands maps subcommand names to their implementations. Running
+// minigo with no subcommand starts the web server.
+var commands = map[string]func(args []string) error{
+	"sgf":  sgfMain,
+	"book": bookMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	serve()
+}
+
+func serve() {
 	root := flag.String("root", "public", "Path to the http public file root")
 	bind := flag.String("bind", "127.0.0.1:4040", "listen address")
+	size := flag.Int("size", web.DefaultSize, "board size")
+	width := flag.Int("width", 0, "board width, if different from -size")
+	height := flag.Int("height", 0, "board height, if different from -size")
+	load := flag.String("load", "", "SGF file to load at startup")
+	openings := flag.String("book", "", "opening book to suggest moves from")
+	problems := flag.String("problems", "", "SGF collection of life-and-death problems to serve")
+	progress := flag.String("progress", "", "file to record progress on -problems in")
+	secretFile := flag.String("secret-file", "", "file holding the key to sign player tokens with; if unset, anyone may play either color")
+	data := flag.String("data", "", "directory to store games in, so that they survive restarts")
+	timeControl := flag.String("time", "", "time control, such as absolute:30m, fischer:5m+10s, byoyomi:10m+5x30s or canadian:10m+25/5m")
 	flag.Parse()
+	var ctrl *clock.Control
+	if *timeControl != "" {
+		var err error
+		if ctrl, err = clock.Parse(*timeControl); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var secret string
+	if *secretFile != "" {
+		buf, err := os.ReadFile(*secretFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		secret = strings.TrimSpace(string(buf))
+		if secret == "" {
+			log.Fatalf("%s: empty secret", *secretFile)
+		}
+	}
 	srv := &web.Server{}
-	srv.Init(&web.Config{Public: *root})
+	if err := srv.Init(&web.Config{
+		Public:   *root,
+		Size:     *size,
+		Width:    *width,
+		Height:   *height,
+		Load:     *load,
+		Book:     *openings,
+		Problems: *problems,
+		Progress: *progress,
+		Secret:   secret,
+		Data:     *data,
+		Clock:    ctrl,
+	}); err != nil {
+		log.Fatal(err)
+	}
 
 	srv.Bind(http.DefaultServeMux)
 	log.Fatal(http.ListenAndServe(*bind, nil))
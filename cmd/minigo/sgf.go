@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nelhage.com/minigo/records"
+	"nelhage.com/minigo/sgf"
+)
+
+var sgfCommands = map[string]func(args []string) error{
+	"lint":    sgfLint,
+	"convert": sgfConvert,
+}
+
+func sgfMain(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: minigo sgf <lint|convert> [args]")
+	}
+	cmd, ok := sgfCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd(args[1:])
+}
+
+// parseFlags registers the flags shared by all subcommands that read
+// SGF input
+func parseFlags(fs *flag.FlagSet) *sgf.Options {
+	opts := &sgf.Options{}
+	fs.BoolVar(&opts.Lenient, "lenient", false, "recover from damaged input")
+	fs.BoolVar(&opts.Compat, "compat", false, "accept FF[1]-FF[3] property identifiers")
+	fs.StringVar(&opts.Charset, "charset", "", "character set of input without a CA property")
+	return opts
+}
+
+func sgfLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	opts := parseFlags(fs)
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("usage: minigo sgf lint [flags] FILE...")
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		ok, err := lintFile(path, *opts, *strict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+		} else if !ok {
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("problems found")
+	}
+	return nil
+}
+
+func lintFile(path string, opts sgf.Options, strict bool) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	c, warns, err := sgf.ParseSGFOptions(f, opts)
+	for _, w := range warns {
+		fmt.Printf("%s:%s: warning: %s\n", path, w.Pos, w.Msg)
+	}
+	if err != nil {
+		return false, err
+	}
+	diags := sgf.Validate(c)
+	for _, d := range diags {
+		fmt.Printf("%s: %s\n", path, d)
+	}
+	if strict && (len(warns) > 0 || len(diags) > 0) {
+		return false, nil
+	}
+	return !sgf.HasErrors(diags), nil
+}
+
+// sgfConvert converts a game record between SGF and its JSON form.
+// Records in the formats understood by package records may also be
+// read.
+func sgfConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	opts := parseFlags(fs)
+	from := fs.String("from", "", "input format: sgf, json, gib, ngf or ugf (default: from the file extension)")
+	to := fs.String("to", "", "output format: sgf or json (default: json for SGF input, sgf otherwise)")
+	output := fs.String("o", "", "output file (default: standard output)")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return errors.New("usage: minigo sgf convert [flags] [FILE]")
+	}
+
+	in, path := io.Reader(os.Stdin), ""
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	if *from == "" {
+		*from = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if *from == "" {
+			*from = "sgf"
+		}
+	}
+	if *to == "" {
+		*to = "sgf"
+		if *from == "sgf" {
+			*to = "json"
+		}
+	}
+
+	c, err := readCollection(in, *from, *opts)
+	if err != nil {
+		if path != "" {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	switch *to {
+	case "sgf":
+		return sgf.WriteSGF(out, c)
+	case "json":
+		return sgf.WriteJSON(out, c)
+	}
+	return fmt.Errorf("unknown output format: %s", *to)
+}
+
+func readCollection(in io.Reader, format string, opts sgf.Options) (*sgf.Collection, error) {
+	switch format {
+	case "sgf":
+		c, warns, err := sgf.ParseSGFOptions(in, opts)
+		for _, w := range warns {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", w.Pos, w.Msg)
+		}
+		return c, err
+	case "json":
+		return sgf.ParseJSON(in)
+	}
+	if parse := records.ForFile("." + format); parse != nil {
+		return parse(in, records.Options{Charset: opts.Charset})
+	}
+	return nil, fmt.Errorf("unknown input format: %s", format)
+}
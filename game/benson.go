@@ -0,0 +1,134 @@
+package game
+
+import "nelhage.com/minigo/bit"
+
+// Life is the result of Benson's algorithm for one color
+type Life struct {
+	// Alive holds the color's stones that are unconditionally
+	// alive: they cannot be captured even if their owner passes
+	// every turn
+	Alive *bit.Vector
+	// Vital holds the regions enclosed by the alive stones that
+	// each give a group one of its eyes. The opponent can never
+	// live there, so any of its stones in them are dead.
+	Vital *bit.Vector
+}
+
+// Benson runs Benson's algorithm for unconditional life for color c.
+//
+// The board is divided into c's chains and regions, the connected
+// areas of points without c's stones. A region is vital to a chain if
+// every empty point in it is a liberty of the chain. The algorithm
+// repeatedly discards chains that have fewer than two vital regions,
+// and regions that border a discarded chain; the chains and regions
+// that are left are alive.
+func (g *Game) Benson(c Color) *Life {
+	b := g.board
+	me := b.black
+	if c == White {
+		me = b.white
+	}
+	empty := b.black.Copy().Or(b.white).Not()
+	chains := b.components(me, me.Copy().Not())
+	regions := b.components(me.Copy().Not(), me)
+
+	// borders[i] lists the chains adjacent to region i, and
+	// vital[i] those to which it is vital
+	borders := make([][]int, len(regions))
+	vital := make([][]int, len(regions))
+	for i, r := range regions {
+		open := r.Copy().And(empty)
+		for j, ch := range chains {
+			libs := b.grow(ch)
+			if libs.Copy().And(r).Popcount() == 0 {
+				continue
+			}
+			borders[i] = append(borders[i], j)
+			if open.Copy().AndNot(libs).Popcount() == 0 {
+				vital[i] = append(vital[i], j)
+			}
+		}
+	}
+
+	chainLive := make([]bool, len(chains))
+	regionLive := make([]bool, len(regions))
+	for i := range chainLive {
+		chainLive[i] = true
+	}
+	for i := range regionLive {
+		regionLive[i] = true
+	}
+	for {
+		eyes := make([]int, len(chains))
+		for i := range regions {
+			if !regionLive[i] {
+				continue
+			}
+			for _, j := range vital[i] {
+				eyes[j]++
+			}
+		}
+		changed := false
+		for j := range chains {
+			if chainLive[j] && eyes[j] < 2 {
+				chainLive[j] = false
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		for i := range regions {
+			for _, j := range borders[i] {
+				if regionLive[i] && !chainLive[j] {
+					regionLive[i] = false
+				}
+			}
+		}
+	}
+
+	out := &Life{
+		Alive: bit.NewVector(me.Len()),
+		Vital: bit.NewVector(me.Len()),
+	}
+	for j, ch := range chains {
+		if chainLive[j] {
+			out.Alive.Or(ch)
+		}
+	}
+	for i, r := range regions {
+		if regionLive[i] && len(vital[i]) > 0 {
+			out.Vital.Or(r)
+		}
+	}
+	return out
+}
+
+// components splits a set of points into its connected parts, each
+// flood-filled within bounds
+func (b *boardState) components(set, bounds *bit.Vector) []*bit.Vector {
+	var out []*bit.Vector
+	left := set.Copy()
+	for i := 0; i < left.Len(); i++ {
+		if !left.At(i) {
+			continue
+		}
+		part := b.floodFill(bit.NewVector(left.Len()).Set(i), bounds)
+		left.AndNot(part)
+		out = append(out, part)
+	}
+	return out
+}
+
+// LifeStatus suggests which stones are alive and which are dead, for
+// marking dead stones at the end of a game. A stone is alive if
+// Benson's algorithm proves it so, and dead if it lies in a region
+// vital to the other color's alive stones. The status of any other
+// stone is left to the players.
+func (g *Game) LifeStatus() (alive, dead *bit.Vector) {
+	black, white := g.Benson(Black), g.Benson(White)
+	alive = black.Alive.Copy().Or(white.Alive)
+	dead = black.Vital.Copy().And(g.board.white)
+	dead.Or(white.Vital.Copy().And(g.board.black))
+	return alive, dead
+}
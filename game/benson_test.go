@@ -0,0 +1,153 @@
+package game
+
+import "testing"
+
+func TestBenson(t *testing.T) {
+	cases := []struct {
+		in    string
+		alive string
+	}{
+		{
+			// Two eyes, one holding a dead white stone
+			`
+0 + O + X + X + + +
+1 X X X X X X + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + O + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + + + X + X + + +
+1 X X X X X X + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			// One eye is not enough, however large
+			`
+0 + + + X + + + + +
+1 X X X X + + + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + + + + + + + + +
+1 + + + + + + + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			// Two chains that share an eye, each with one of
+			// its own
+			`
+0 + X + X + X + + +
+1 X X + X X X + + +
+2 + X X + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + X + X + X + + +
+1 X X + X X X + + +
+2 + X X + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			// Without its own eye, the right chain dies, and
+			// the shared eye no longer counts for the left
+			`
+0 + X + X + + + + +
+1 X X + X X + + + +
+2 + X X + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + + + + + + + + +
+1 + + + + + + + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+	}
+	for i, tc := range cases {
+		g := New(9)
+		g.board = board(g, tc.in)
+		want := board(g, tc.alive)
+		if life := g.Benson(Black); !life.Alive.Equal(want.black) {
+			t.Errorf("case %d: alive=\n%s\nwant=\n%s", i,
+				&boardState{g: g, black: life.Alive, white: want.white}, want)
+		}
+	}
+}
+
+func TestLifeStatus(t *testing.T) {
+	g := New(9)
+	g.board = board(g, `
+0 + O + X + X O + +
+1 X X X X X X O + +
+2 O O O O O O O + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`)
+	alive, dead := g.LifeStatus()
+	if alive.Popcount() != 8 || !alive.At(3) || !alive.At(9) || alive.At(6) {
+		t.Errorf("alive: %v", alive)
+	}
+	if dead.Popcount() != 1 || !dead.At(1) {
+		t.Errorf("dead: %v", dead)
+	}
+	life := g.Benson(Black)
+	if life.Vital.Popcount() != 4 || !life.Vital.At(0) || !life.Vital.At(4) {
+		t.Errorf("vital: %v", life.Vital)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"nelhage.com/minigo/bit"
+	"nelhage.com/minigo/sgf"
 )
 
 var (
@@ -39,20 +40,50 @@ type boardState struct {
 	blackPrisoners, whitePrisoners int
 	toPlay                         Color
 	passes                         int
+	// moves counts the moves, including passes, that led to this
+	// position
+	moves int
+
+	// lastX and lastY are the move that produced this position,
+	// or -1,-1 for a pass or the initial position
+	lastX, lastY int
+	// setup is true if this position was produced by placing or
+	// removing stones directly, rather than by a move
+	setup bool
+	// notesOnly is true if this position repeats prev unchanged,
+	// to hold the annotations of an SGF node without a move or
+	// setup
+	notesOnly bool
+
+	// notes holds SGF annotations for this position, if it was
+	// loaded from SGF. dimmed is the set of points dimmed by the
+	// most recent DD property.
+	notes  *sgf.Annotations
+	dimmed []sgf.Point
+	// timeLeft is the time the player who made the move had left
+	// after it, if it was recorded
+	timeLeft *TimeLeft
 }
 
 func (b *boardState) move(x, y int) (*boardState, error) {
 	out := *b
 	out.prev = b
+	out.setup = false
+	out.notesOnly = false
+	out.notes = nil
+	out.timeLeft = nil
 	out.toPlay = !out.toPlay
+	out.lastX, out.lastY = x, y
+	out.moves++
 	if x < 0 && y < 0 {
+		out.lastX, out.lastY = -1, -1
 		out.passes++
 		return &out, nil
 	}
-	if x < 0 || x >= b.g.Size || y < 0 || y >= b.g.Size {
+	if x < 0 || x >= b.g.Width || y < 0 || y >= b.g.Height {
 		return nil, ErrOutOfBounds
 	}
-	idx := y*b.g.Size + x
+	idx := y*b.g.Width + x
 	if b.white.At(idx) || b.black.At(idx) {
 		return nil, ErrOccupied
 	}
@@ -73,20 +104,20 @@ func (b *boardState) move(x, y int) (*boardState, error) {
 			*prisoners += c.Popcount()
 		}
 	}
-	if x < b.g.Size-1 {
+	if x < b.g.Width-1 {
 		if c := out.deadGroupAt(idx+1, *them, *me); c != nil {
 			*them = (*them).Copy().AndNot(c)
 			*prisoners += c.Popcount()
 		}
 	}
 	if y > 0 {
-		if c := out.deadGroupAt(idx-b.g.Size, *them, *me); c != nil {
+		if c := out.deadGroupAt(idx-b.g.Width, *them, *me); c != nil {
 			*them = (*them).Copy().AndNot(c)
 			*prisoners += c.Popcount()
 		}
 	}
-	if y < b.g.Size-1 {
-		if c := out.deadGroupAt(idx+b.g.Size, *them, *me); c != nil {
+	if y < b.g.Height-1 {
+		if c := out.deadGroupAt(idx+b.g.Width, *them, *me); c != nil {
 			*them = (*them).Copy().AndNot(c)
 			*prisoners += c.Popcount()
 		}
@@ -95,8 +126,8 @@ func (b *boardState) move(x, y int) (*boardState, error) {
 		return nil, ErrSelfCapture
 	}
 
-	if b.prev != nil && b.prev.white.Equal(out.white) &&
-		b.prev.black.Equal(out.black) {
+	if p := b.played(); p.prev != nil && p.prev.white.Equal(out.white) &&
+		p.prev.black.Equal(out.black) {
 		return nil, ErrKo
 	}
 
@@ -104,6 +135,15 @@ func (b *boardState) move(x, y int) (*boardState, error) {
 	return &out, nil
 }
 
+// played returns the position that b repeats, skipping any positions
+// that only hold annotations
+func (b *boardState) played() *boardState {
+	for b.notesOnly {
+		b = b.prev
+	}
+	return b
+}
+
 func (b *boardState) gameOver() bool {
 	return b.passes >= 2
 }
@@ -121,8 +161,8 @@ func (b *boardState) grow(root *bit.Vector) *bit.Vector {
 	next := root.Copy()
 	next.Or(root.Copy().Lsh(1).AndNot(b.g.r))
 	next.Or(root.Copy().Rsh(1).AndNot(b.g.l))
-	next.Or(root.Copy().Lsh(uint(b.g.Size)))
-	next.Or(root.Copy().Rsh(uint(b.g.Size)))
+	next.Or(root.Copy().Lsh(uint(b.g.Width)))
+	next.Or(root.Copy().Rsh(uint(b.g.Width)))
 	return next
 }
 
@@ -139,7 +179,7 @@ func (b *boardState) floodFill(root *bit.Vector, bounds *bit.Vector) *bit.Vector
 }
 
 func (b *boardState) at(x, y int) (Color, bool) {
-	bit := y*b.g.Size + x
+	bit := y*b.g.Width + x
 	if b.white.At(bit) {
 		return White, true
 	}
@@ -151,9 +191,9 @@ func (b *boardState) at(x, y int) (Color, bool) {
 
 func (b *boardState) String() string {
 	out := &bytes.Buffer{}
-	for r := 0; r < b.g.Size; r++ {
+	for r := 0; r < b.g.Height; r++ {
 		fmt.Fprintf(out, "% 2d", r)
-		for c := 0; c < b.g.Size; c++ {
+		for c := 0; c < b.g.Width; c++ {
 			c, ok := b.at(c, r)
 			switch {
 			case !ok:
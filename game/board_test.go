@@ -12,29 +12,29 @@ import (
 var fixtureRE = regexp.MustCompile(`\A\s*((?:\d+\s*(?:[OX+*]\s*)+\n)+)`)
 
 func board(g *Game, in string) *boardState {
-	white := bit.NewVector(g.Size * g.Size)
-	black := bit.NewVector(g.Size * g.Size)
+	white := bit.NewVector(g.Width * g.Height)
+	black := bit.NewVector(g.Width * g.Height)
 
 	m := fixtureRE.FindStringSubmatch(in)
 	if m == nil {
 		panic(fmt.Sprintf("bad fixture:\n%s", in))
 	}
 	lines := strings.Split(strings.TrimRight(m[1], "\n"), "\n")
-	if len(lines) != g.Size {
+	if len(lines) != g.Height {
 		panic(fmt.Sprintf("bad fixture (%d rows):\n%#v", len(lines), lines))
 	}
 	for i, l := range lines {
 		bits := strings.Split(l, " ")
-		if len(bits) != g.Size+1 {
+		if len(bits) != g.Width+1 {
 			panic(fmt.Sprintf("bad fixture:\n%s", in))
 		}
 		for j, c := range bits[1:] {
 			switch c {
 			case "*", "+":
 			case "O":
-				white.Set(g.Size*i + j)
+				white.Set(g.Width*i + j)
 			case "X":
-				black.Set(g.Size*i + j)
+				black.Set(g.Width*i + j)
 			}
 		}
 	}
@@ -365,12 +365,21 @@ func TestKo(t *testing.T) {
 8 + + + + + + + + +
   0 1 2 3 4 5 6 7 8
 `, White)
+	if _, _, ok := g.KoPoint(); ok {
+		t.Error("ko point before capture")
+	}
 	if err := g.Move(8, 5); err != nil {
 		t.Fatal("move:", err)
 	}
+	if x, y, ok := g.KoPoint(); !ok || x != 8 || y != 4 {
+		t.Errorf("KoPoint() = (%d,%d,%v)", x, y, ok)
+	}
 	if err := g.Move(8, 4); err != ErrKo {
 		t.Fatal("ko:", err)
 	}
+	if b, w := g.Prisoners(); b != 0 || w != 1 {
+		t.Errorf("Prisoners() = (%d,%d)", b, w)
+	}
 }
 
 func TestPass(t *testing.T) {
@@ -387,7 +396,111 @@ func TestPass(t *testing.T) {
 	if g.board.passes != 2 {
 		t.Errorf("passes %d != 2", g.board.passes)
 	}
+	if g.MoveNumber() != 2 {
+		t.Errorf("MoveNumber() = %d", g.MoveNumber())
+	}
 	if !g.GameOver() {
 		t.Fatal("not over")
 	}
 }
+
+func TestResign(t *testing.T) {
+	g := New(9)
+	if err := g.Move(4, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Resign(White); err != nil {
+		t.Fatal("Resign:", err)
+	}
+	if !g.GameOver() || g.Info.Result != "B+R" {
+		t.Errorf("over=%v result=%q", g.GameOver(), g.Info.Result)
+	}
+	if err := g.Move(3, 3); err != ErrGameOver {
+		t.Errorf("move after resigning: %v", err)
+	}
+	if err := g.Resign(Black); err != ErrGameOver {
+		t.Errorf("resign twice: %v", err)
+	}
+	if !g.Undo() || g.GameOver() || g.Info.Result != "" || g.MoveNumber() != 1 {
+		t.Errorf("undo resignation: over=%v result=%q", g.GameOver(), g.Info.Result)
+	}
+	if err := g.TimeOut(White); err != nil || g.Info.Result != "B+T" {
+		t.Errorf("TimeOut: %v, result=%q", err, g.Info.Result)
+	}
+	if g.Undo() || !g.GameOver() || g.MoveNumber() != 1 {
+		t.Errorf("undo loss on time: over=%v moves=%d", g.GameOver(), g.MoveNumber())
+	}
+}
+
+func TestRect(t *testing.T) {
+	g := NewRect(7, 4)
+	g.board = board(g, `
+0 + + + + + + +
+1 + + + + + X O
+2 + + + + + + X
+3 + + + + + + +
+  0 1 2 3 4 5 6
+`)
+	if err := g.Move(7, 0); err != ErrOutOfBounds {
+		t.Fatalf("Move(7,0): %v", err)
+	}
+	if err := g.Move(0, 4); err != ErrOutOfBounds {
+		t.Fatalf("Move(0,4): %v", err)
+	}
+	if err := g.Move(6, 0); err != nil {
+		t.Fatalf("Move(6,0): %v", err)
+	}
+	if _, ok := g.At(6, 1); ok {
+		t.Errorf("stone at (6,1) was not captured\n%s", g.board)
+	}
+	if c, ok := g.At(6, 3); ok {
+		t.Errorf("At(6,3) = (%v,%v)", c, ok)
+	}
+	if err := g.Move(0, 3); err != nil {
+		t.Fatalf("Move(0,3): %v", err)
+	}
+	if err := g.Move(6, 3); err != nil {
+		t.Fatalf("Move(6,3): %v", err)
+	}
+	if err := g.Move(0, 1); err != nil {
+		t.Fatalf("Move(0,1): %v", err)
+	}
+	if c, ok := g.At(0, 3); !ok || c != White {
+		t.Errorf("At(0,3) = (%v,%v)\n%s", c, ok, g.board)
+	}
+}
+
+func TestChain(t *testing.T) {
+	g := game(5, `
+0 + X + + +
+1 X X O + +
+2 + O + + +
+3 + + + + +
+4 + + + + +
+  0 1 2 3 4
+`, White)
+	chain := g.Chain(1, 1)
+	if chain.Popcount() != 3 || !chain.At(1) || !chain.At(5) || !chain.At(6) {
+		t.Errorf("Chain(1,1) = %v", chain)
+	}
+	libs := g.Liberties(chain)
+	if libs.Popcount() != 3 || !libs.At(0) || !libs.At(2) || !libs.At(10) {
+		t.Errorf("Liberties = %v", libs)
+	}
+	if g.Chain(3, 3).Popcount() != 0 {
+		t.Error("Chain of an empty point is not empty")
+	}
+
+	if err := g.Move(0, 2); err != nil {
+		t.Fatalf("Move(0,2): %v", err)
+	}
+	if !g.Undo() {
+		t.Fatal("Undo failed")
+	}
+	if _, ok := g.At(0, 2); ok || g.ToPlay() != White {
+		t.Error("Undo did not restore the position")
+	}
+	if g.Undo() {
+		t.Error("Undo at the start of the game succeeded")
+	}
+}
@@ -0,0 +1,172 @@
+package game
+
+// Estimate is an estimate of who will own each point of the board at
+// the end of the game
+type Estimate struct {
+	Width, Height int
+	// Ownership holds a score for each point, indexed by
+	// y*Width+x, from 1 if black owns it to -1 if white does
+	Ownership []float64
+	// Margin is black's estimated lead under area scoring, before
+	// komi
+	Margin float64
+}
+
+// Parameters for Bouzy's algorithm, as in GNU Go's "5/21"
+const (
+	dilations  = 5
+	erosions   = 21
+	stoneValue = 128
+	// fullOwnership is the influence at which a point outside
+	// enclosed territory counts as wholly owned
+	fullOwnership = 16
+)
+
+// Estimate estimates the final ownership of the board using Bouzy's
+// mathematical morphology: each stone radiates influence, which
+// spreads by dilation over points no opponent influence touches, and
+// is then eroded at the boundaries between the two sides, leaving the
+// areas each side controls.
+//
+// Stones that LifeStatus finds dead are removed first, and empty
+// regions that are bordered by one side only and that the algorithm
+// gives entirely to that side count as wholly owned, so that settled
+// territory is counted in full.
+func (g *Game) Estimate() *Estimate {
+	n := g.Width * g.Height
+	_, dead := g.LifeStatus()
+	v := make([]int, n)
+	for i := 0; i < n; i++ {
+		switch {
+		case dead.At(i):
+		case g.board.black.At(i):
+			v[i] = stoneValue
+		case g.board.white.At(i):
+			v[i] = -stoneValue
+		}
+	}
+	nbrs := g.neighbors()
+	for i := 0; i < dilations; i++ {
+		v = dilate(v, nbrs)
+	}
+	for i := 0; i < erosions; i++ {
+		v = erode(v, nbrs)
+	}
+
+	e := &Estimate{Width: g.Width, Height: g.Height, Ownership: make([]float64, n)}
+	for i, x := range v {
+		o := float64(x) / fullOwnership
+		if o > 1 {
+			o = 1
+		} else if o < -1 {
+			o = -1
+		}
+		e.Ownership[i] = o
+		switch {
+		case x > 0:
+			e.Margin++
+		case x < 0:
+			e.Margin--
+		}
+	}
+	g.fillTerritory(e.Ownership, v)
+	return e
+}
+
+// fillTerritory sets the ownership of each empty region that only one
+// side's stones border, and whose points all have that side's
+// influence, to 1 or -1
+func (g *Game) fillTerritory(own []float64, v []int) {
+	b := g.board
+	stones := b.black.Copy().Or(b.white)
+	for _, r := range b.components(stones.Copy().Not(), stones) {
+		edge := b.grow(r).AndNot(r)
+		var want float64
+		switch {
+		case edge.Copy().AndNot(b.black).Popcount() == 0:
+			want = 1
+		case edge.Copy().AndNot(b.white).Popcount() == 0:
+			want = -1
+		default:
+			continue
+		}
+		owned := true
+		for i := 0; i < r.Len() && owned; i++ {
+			owned = !r.At(i) || float64(v[i])*want > 0
+		}
+		if !owned {
+			continue
+		}
+		for i := 0; i < r.Len(); i++ {
+			if r.At(i) {
+				own[i] = want
+			}
+		}
+	}
+}
+
+// neighbors lists the points adjacent to each point of the board
+func (g *Game) neighbors() [][]int {
+	out := make([][]int, g.Width*g.Height)
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			i := y*g.Width + x
+			if x > 0 {
+				out[i] = append(out[i], i-1)
+			}
+			if x < g.Width-1 {
+				out[i] = append(out[i], i+1)
+			}
+			if y > 0 {
+				out[i] = append(out[i], i-g.Width)
+			}
+			if y < g.Height-1 {
+				out[i] = append(out[i], i+g.Width)
+			}
+		}
+	}
+	return out
+}
+
+// dilate spreads influence: a point not touched by the opponent's
+// influence gains one for each neighbor with its own
+func dilate(v []int, nbrs [][]int) []int {
+	out := make([]int, len(v))
+	for i, x := range v {
+		pos, neg := 0, 0
+		for _, j := range nbrs[i] {
+			switch {
+			case v[j] > 0:
+				pos++
+			case v[j] < 0:
+				neg++
+			}
+		}
+		switch {
+		case x >= 0 && neg == 0:
+			x += pos
+		case x <= 0 && pos == 0:
+			x -= neg
+		}
+		out[i] = x
+	}
+	return out
+}
+
+// erode shrinks influence: a point loses one for each neighbor
+// without its own, stopping at zero
+func erode(v []int, nbrs [][]int) []int {
+	out := make([]int, len(v))
+	for i, x := range v {
+		for _, j := range nbrs[i] {
+			switch {
+			case x > 0 && v[j] <= 0:
+				x--
+			case x < 0 && v[j] >= 0:
+				x++
+			}
+		}
+		out[i] = x
+	}
+	return out
+}
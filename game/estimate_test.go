@@ -0,0 +1,65 @@
+package game
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	g := New(9)
+	g.board = board(g, `
+0 + + + + X O + + +
+1 + + + + X O + + +
+2 + + + + X O + + +
+3 + + + + X O + + +
+4 + + + + X O + + +
+5 + + + + X O + + +
+6 + + + + X O + + +
+7 + + + + X O + + +
+8 + + + + X O + + +
+  0 1 2 3 4 5 6 7 8
+`)
+	e := g.Estimate()
+	if e.Margin != 9 {
+		t.Errorf("margin %v, want 9", e.Margin)
+	}
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			want := 1.0
+			if x > 4 {
+				want = -1
+			}
+			if o := e.Ownership[y*9+x]; o != want {
+				t.Errorf("ownership(%d,%d) = %v, want %v", x, y, o, want)
+			}
+		}
+	}
+}
+
+func TestEstimateSymmetric(t *testing.T) {
+	g := New(9)
+	g.board = board(g, `
+0 + + + + + + + + +
+1 + + + + + + + + +
+2 + + X + + + O + +
+3 + + + + + + + + +
+4 + + X + + + O + +
+5 + + + + + + + + +
+6 + + X + + + O + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`)
+	e := g.Estimate()
+	if e.Margin != 0 {
+		t.Errorf("margin %v, want 0", e.Margin)
+	}
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			l, r := e.Ownership[y*9+x], e.Ownership[y*9+8-x]
+			if l != -r {
+				t.Errorf("ownership(%d,%d)=%v ownership(%d,%d)=%v", x, y, l, 8-x, y, r)
+			}
+		}
+	}
+	if e.Ownership[2*9+2] != 1 || e.Ownership[4*9+4] != 0 || e.Ownership[4*9+1] <= 0 {
+		t.Errorf("ownership: %v", e.Ownership)
+	}
+}
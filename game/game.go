@@ -12,10 +12,43 @@ var (
 	Black Color = false
 )
 
+// Info holds facts about a game that do not affect play, as recorded
+// in the root node of its SGF
+type Info struct {
+	// Black and White are the players' names
+	Black, White string
+	Komi         float64
+	// Rules names the rules in use, as in SGF's RU property
+	Rules string
+	// Result is the recorded result, in the format of SGF's RE
+	// property, if there is one
+	Result string
+	// TimeLimit is each player's main time in seconds, as in
+	// SGF's TM property, and Overtime describes the overtime
+	// system, as in OT
+	TimeLimit float64
+	Overtime  string
+}
+
+// TimeLeft is the time a player has left after a move, as recorded in
+// SGF's BL/WL and OB/OW properties
+type TimeLeft struct {
+	Seconds float64
+	// Periods is the number of overtime periods, or of stones to
+	// play in the current Canadian period, left. It is zero
+	// outside overtime.
+	Periods int
+}
+
 // Game represents a game of Go
 type Game struct {
-	Size  int
-	board *boardState
+	Width, Height int
+	Info          Info
+	board         *boardState
+	// forfeited is set if a player has resigned, to "R", or lost
+	// on time, to "T", and loser to that player
+	forfeited string
+	loser     Color
 
 	l, r, t, b *bit.Vector
 	z          *bit.Vector
@@ -23,28 +56,38 @@ type Game struct {
 
 // New returns a new game of board size `size` on a side
 func New(size int) *Game {
-	g := &Game{Size: size}
+	return NewRect(size, size)
+}
+
+// NewRect returns a new game on a board `width` columns wide and
+// `height` rows high
+func NewRect(width, height int) *Game {
+	g := &Game{Width: width, Height: height}
 	g.board = &boardState{
 		g:      g,
-		white:  bit.NewVector(size * size),
-		black:  bit.NewVector(size * size),
+		white:  bit.NewVector(width * height),
+		black:  bit.NewVector(width * height),
 		toPlay: Black,
+		lastX:  -1,
+		lastY:  -1,
 	}
 	g.precompute()
 	return g
 }
 
 func (g *Game) precompute() {
-	g.l = bit.NewVector(g.Size * g.Size)
-	g.r = bit.NewVector(g.Size * g.Size)
-	g.t = bit.NewVector(g.Size * g.Size)
-	g.b = bit.NewVector(g.Size * g.Size)
-	g.z = bit.NewVector(g.Size * g.Size)
-	for i := 0; i < g.Size; i++ {
-		g.l.Set(i * g.Size)
-		g.r.Set((i+1)*g.Size - 1)
+	g.l = bit.NewVector(g.Width * g.Height)
+	g.r = bit.NewVector(g.Width * g.Height)
+	g.t = bit.NewVector(g.Width * g.Height)
+	g.b = bit.NewVector(g.Width * g.Height)
+	g.z = bit.NewVector(g.Width * g.Height)
+	for i := 0; i < g.Height; i++ {
+		g.l.Set(i * g.Width)
+		g.r.Set((i+1)*g.Width - 1)
+	}
+	for i := 0; i < g.Width; i++ {
 		g.t.Set(i)
-		g.b.Set(g.Size*(g.Size-1) + i)
+		g.b.Set(g.Width*(g.Height-1) + i)
 	}
 }
 
@@ -55,13 +98,42 @@ func (g *Game) ToPlay() Color {
 
 // GameOver returns true if the game is over
 func (g *Game) GameOver() bool {
-	return g.board.gameOver()
+	return g.forfeited != "" || g.board.gameOver()
+}
+
+// Resign ends the game with a win for c's opponent, recording the
+// result in Info
+func (g *Game) Resign(c Color) error {
+	return g.forfeit(c, "R")
+}
+
+// Resigned returns the player who resigned, if the game ended that way
+func (g *Game) Resigned() (Color, bool) {
+	return g.loser, g.forfeited == "R"
+}
+
+// TimeOut ends the game with a win for c's opponent because c has run
+// out of time, recording the result in Info
+func (g *Game) TimeOut(c Color) error {
+	return g.forfeit(c, "T")
+}
+
+func (g *Game) forfeit(c Color, reason string) error {
+	if g.GameOver() {
+		return ErrGameOver
+	}
+	g.forfeited, g.loser = reason, c
+	g.Info.Result = "B+" + reason
+	if c == Black {
+		g.Info.Result = "W+" + reason
+	}
+	return nil
 }
 
 // Move plays a stone at position (x,y). A move at -1,-1 acts as a
 // pass.
 func (g *Game) Move(x, y int) error {
-	if g.board.gameOver() {
+	if g.GameOver() {
 		return ErrGameOver
 	}
 	b, err := g.board.move(x, y)
@@ -78,3 +150,131 @@ func (g *Game) Move(x, y int) error {
 func (g *Game) At(x, y int) (Color, bool) {
 	return g.board.at(x, y)
 }
+
+// LastMove returns the most recent move and the player who made it. A
+// pass is reported as -1,-1. ok is false if no move has been played
+// since the start of the game or the last change to the setup.
+func (g *Game) LastMove() (x, y int, c Color, ok bool) {
+	b := g.board.played()
+	if b.prev == nil || b.setup {
+		return -1, -1, Black, false
+	}
+	return b.lastX, b.lastY, b.prev.toPlay, true
+}
+
+// Prisoners returns the number of stones each player has captured
+func (g *Game) Prisoners() (black, white int) {
+	return g.board.blackPrisoners, g.board.whitePrisoners
+}
+
+// Passes returns the number of consecutive passes that ended in the
+// current position
+func (g *Game) Passes() int {
+	return g.board.passes
+}
+
+// KoPoint returns the point where the player to play may not move
+// because it would retake a ko, if there is one
+func (g *Game) KoPoint() (x, y int, ok bool) {
+	b := g.board.played()
+	if b.prev == nil || b.setup || b.lastX < 0 {
+		return -1, -1, false
+	}
+	them := b.prev.white
+	if b.toPlay == Black {
+		them = b.prev.black
+	}
+	captured := them.Copy().AndNot(b.black).AndNot(b.white)
+	if captured.Popcount() != 1 {
+		return -1, -1, false
+	}
+	i := 0
+	for !captured.At(i) {
+		i++
+	}
+	x, y = i%g.Width, i/g.Width
+	if _, err := b.move(x, y); err != ErrKo {
+		return -1, -1, false
+	}
+	return x, y, true
+}
+
+// SetTimeLeft records the time the player who made the last move had
+// left after it. It has no effect if no move has been played.
+func (g *Game) SetTimeLeft(t TimeLeft) {
+	if _, _, _, ok := g.LastMove(); !ok {
+		return
+	}
+	b := *g.board
+	b.timeLeft = &t
+	g.board = &b
+}
+
+// TimeLeft returns the time recorded for the player who made the last
+// move, if any
+func (g *Game) TimeLeft() (TimeLeft, bool) {
+	if g.board.timeLeft == nil {
+		return TimeLeft{}, false
+	}
+	return *g.board.timeLeft, true
+}
+
+// MoveNumber returns the number of moves, including passes, played
+// to reach the current position
+func (g *Game) MoveNumber() int {
+	return g.board.moves
+}
+
+// Copy returns a copy of the game, which can be played independently
+// of the original. Positions already played are shared, since moves
+// never modify them.
+func (g *Game) Copy() *Game {
+	out := *g
+	return &out
+}
+
+// TimedOut returns the player who lost on time, if the game ended that
+// way
+func (g *Game) TimedOut() (Color, bool) {
+	return g.loser, g.forfeited == "T"
+}
+
+// Undo takes back a resignation, or else the most recent move or
+// setup change, returning false at the start of the game. A loss on
+// time cannot be taken back, and Undo returns false after one.
+func (g *Game) Undo() bool {
+	if g.forfeited == "T" {
+		return false
+	}
+	if g.forfeited != "" {
+		g.forfeited = ""
+		g.Info.Result = ""
+		return true
+	}
+	if g.board.prev == nil {
+		return false
+	}
+	g.board = g.board.prev
+	return true
+}
+
+// Chain returns the set of stones connected to the stone at (x,y), as
+// a bit vector indexed by y*Width+x. It is empty if there is no stone
+// at (x,y).
+func (g *Game) Chain(x, y int) *bit.Vector {
+	out := bit.NewVector(g.Width * g.Height)
+	c, ok := g.board.at(x, y)
+	if !ok {
+		return out
+	}
+	me := g.board.black
+	if c == White {
+		me = g.board.white
+	}
+	return g.board.floodFill(out.Set(y*g.Width+x), me.Copy().Not())
+}
+
+// Liberties returns the empty points adjacent to a set of stones
+func (g *Game) Liberties(stones *bit.Vector) *bit.Vector {
+	return g.board.grow(stones).AndNot(g.board.black).AndNot(g.board.white)
+}
@@ -0,0 +1,211 @@
+package game
+
+import (
+	"nelhage.com/minigo/bit"
+	"nelhage.com/minigo/sgf"
+)
+
+// maxLadderNodes bounds the positions ReadLadder examines
+const maxLadderNodes = 10000
+
+// Ladder is the result of reading a ladder
+type Ladder struct {
+	// Captured is true if the chain is captured however it runs
+	Captured bool
+	// Attacker is the player trying to capture the chain
+	Attacker Color
+	// Moves is the main line of the ladder, in which the players
+	// alternate starting with First. If the chain is captured, it
+	// ends when the chain is left in atari with no way out;
+	// otherwise it ends with the defender's escape.
+	Moves []sgf.Point
+	First Color
+}
+
+type ladderReader struct {
+	width    int
+	target   int
+	attacker Color
+	nodes    int
+}
+
+// ReadLadder reads out whether the chain containing the stone at
+// (x,y) can be captured in a ladder. If the chain is in atari, its
+// owner moves first, extending or capturing a neighboring chain in
+// atari; if it has two liberties, the attacker moves first, playing
+// on one of them. The attacker must keep the chain in atari with
+// every move. Stones the chain can run into, ladder breakers, and
+// attacking stones it can capture are all taken into account.
+//
+// ok is false if there is no stone at (x,y) or its chain has more
+// than two liberties. Ladders too long to read are reported as not
+// captured.
+func (g *Game) ReadLadder(x, y int) (l *Ladder, ok bool) {
+	c, ok := g.At(x, y)
+	if !ok {
+		return nil, false
+	}
+	r := &ladderReader{width: g.Width, target: y*g.Width + x, attacker: !c}
+	b := *g.board
+	libs, _ := r.liberties(&b)
+	l = &Ladder{Attacker: r.attacker}
+	var line []int
+	switch libs.Popcount() {
+	case 1:
+		l.First = c
+		l.Captured, line = r.defend(&b)
+	case 2:
+		l.First = r.attacker
+		l.Captured, line = r.attack(&b)
+	default:
+		return nil, false
+	}
+	for _, i := range line {
+		l.Moves = append(l.Moves, sgf.Point{X: i % g.Width, Y: i / g.Width})
+	}
+	return l, true
+}
+
+// Ladders returns one stone from each chain that the player to play
+// can capture in a ladder, or that the opponent can if the player to
+// play tries to run out of atari
+func (g *Game) Ladders() []sgf.Point {
+	var out []sgf.Point
+	b := g.board
+	for _, c := range []Color{Black, White} {
+		me := b.black
+		if c == White {
+			me = b.white
+		}
+		for _, chain := range b.components(me, me.Copy().Not()) {
+			libs := g.Liberties(chain).Popcount()
+			if libs == 1 && c != b.toPlay || libs == 2 && c == b.toPlay || libs > 2 {
+				continue
+			}
+			i := 0
+			for !chain.At(i) {
+				i++
+			}
+			if l, ok := g.ReadLadder(i%g.Width, i/g.Width); ok && l.Captured {
+				out = append(out, sgf.Point{X: i % g.Width, Y: i / g.Width})
+			}
+		}
+	}
+	return out
+}
+
+// liberties returns the target chain and its liberties
+func (r *ladderReader) liberties(b *boardState) (libs, chain *bit.Vector) {
+	me := b.black
+	if r.attacker == Black {
+		me = b.white
+	}
+	chain = b.floodFill(bit.NewVector(me.Len()).Set(r.target), me.Copy().Not())
+	return b.grow(chain).AndNot(b.black).AndNot(b.white), chain
+}
+
+func (r *ladderReader) play(b *boardState, c Color, idx int) (*boardState, bool) {
+	s := *b
+	s.toPlay = c
+	next, err := s.move(idx%r.width, idx/r.width)
+	return next, err == nil
+}
+
+// attack tries each move that puts the chain, which has two
+// liberties, in atari. It returns whether one captures the chain, and
+// the line that does, or the longest line tried if none does.
+func (r *ladderReader) attack(b *boardState) (bool, []int) {
+	r.nodes++
+	if r.nodes > maxLadderNodes {
+		return false, nil
+	}
+	libs, _ := r.liberties(b)
+	var longest []int
+	for i := 0; i < libs.Len(); i++ {
+		if !libs.At(i) {
+			continue
+		}
+		next, ok := r.play(b, r.attacker, i)
+		if !ok {
+			continue
+		}
+		if l, _ := r.liberties(next); l.Popcount() != 1 {
+			continue
+		}
+		captured, line := r.defend(next)
+		line = append([]int{i}, line...)
+		if captured {
+			return true, line
+		}
+		if len(line) > len(longest) {
+			longest = line
+		}
+	}
+	return false, longest
+}
+
+// defend tries each way for the chain, which is in atari, to escape:
+// extending at its liberty, or capturing an attacking chain in atari.
+// It returns whether all of them fail, and the line of the first
+// failure, or of the escape.
+func (r *ladderReader) defend(b *boardState) (bool, []int) {
+	libs, chain := r.liberties(b)
+	lib := 0
+	for !libs.At(lib) {
+		lib++
+	}
+	moves := []int{lib}
+	them := b.black
+	if r.attacker == White {
+		them = b.white
+	}
+	near := b.grow(chain).And(them)
+	for i := 0; i < near.Len(); i++ {
+		if !near.At(i) {
+			continue
+		}
+		group := b.floodFill(bit.NewVector(them.Len()).Set(i), them.Copy().Not())
+		near.AndNot(group)
+		glibs := b.grow(group).AndNot(b.black).AndNot(b.white)
+		if glibs.Popcount() != 1 {
+			continue
+		}
+		j := 0
+		for !glibs.At(j) {
+			j++
+		}
+		if j != lib {
+			moves = append(moves, j)
+		}
+	}
+
+	var first []int
+	for _, m := range moves {
+		next, ok := r.play(b, !r.attacker, m)
+		if !ok {
+			continue
+		}
+		nl, _ := r.liberties(next)
+		var line []int
+		switch n := nl.Popcount(); {
+		case n >= 3:
+			return false, []int{m}
+		case n == 2:
+			captured, rest := r.attack(next)
+			line = append([]int{m}, rest...)
+			if !captured {
+				return false, line
+			}
+		default:
+			line = []int{m}
+		}
+		if first == nil {
+			first = line
+		}
+	}
+	if first == nil {
+		// The chain cannot move; the attacker captures it
+		return true, nil
+	}
+	return true, first
+}
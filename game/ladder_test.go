@@ -0,0 +1,94 @@
+package game
+
+import (
+	"testing"
+
+	"nelhage.com/minigo/sgf"
+)
+
+const ladderStart = `
+0 + + + + + + + X +
+1 + + + + + + X O X
+2 + + + + + + + + X
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`
+
+func TestLadder(t *testing.T) {
+	g := game(9, ladderStart, White)
+	l, ok := g.ReadLadder(7, 1)
+	if !ok {
+		t.Fatal("no ladder")
+	}
+	if !l.Captured || l.Attacker != Black || l.First != White {
+		t.Fatalf("ladder: %+v", l)
+	}
+	// The ladder runs down and to the left, to the bottom edge
+	want := []sgf.Point{{X: 7, Y: 2}, {X: 7, Y: 3}, {X: 6, Y: 2}, {X: 5, Y: 2}, {X: 6, Y: 3}}
+	for i, p := range want {
+		if l.Moves[i] != p {
+			t.Fatalf("moves: %v", l.Moves)
+		}
+	}
+	if last := l.Moves[len(l.Moves)-1]; last.Y != 8 {
+		t.Errorf("ladder ends at %v", last)
+	}
+
+	if pts := g.Ladders(); len(pts) != 1 || pts[0] != (sgf.Point{X: 7, Y: 1}) {
+		t.Errorf("Ladders: %v", pts)
+	}
+	// With black to play, the stone is simply captured
+	g.board.toPlay = Black
+	if pts := g.Ladders(); len(pts) != 0 {
+		t.Errorf("Ladders with black to play: %v", pts)
+	}
+}
+
+func TestLadderBreaker(t *testing.T) {
+	g := game(9, ladderStart, White)
+	g.board.white.Set(5*9 + 2)
+	l, ok := g.ReadLadder(7, 1)
+	if !ok {
+		t.Fatal("no ladder")
+	}
+	if l.Captured {
+		t.Fatalf("captured despite the breaker: %v", l.Moves)
+	}
+	if last := l.Moves[len(l.Moves)-1]; last.X > 4 {
+		t.Errorf("escape line ends at %v", last)
+	}
+}
+
+func TestLadderCountercapture(t *testing.T) {
+	g := game(9, `
+0 + + + + + + + + +
+1 + O X O + + + + +
+2 + X O X + + + + +
+3 + X + X + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, White)
+	l, ok := g.ReadLadder(2, 2)
+	if !ok {
+		t.Fatal("no ladder")
+	}
+	if l.Captured || len(l.Moves) == 0 || l.Moves[0] != (sgf.Point{X: 2, Y: 0}) {
+		t.Errorf("ladder: %+v", l)
+	}
+
+	if _, ok := g.ReadLadder(1, 2); ok {
+		t.Error("ladder read for a chain with many liberties")
+	}
+	if _, ok := g.ReadLadder(0, 0); ok {
+		t.Error("ladder read for an empty point")
+	}
+}
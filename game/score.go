@@ -0,0 +1,143 @@
+package game
+
+import (
+	"strconv"
+
+	"nelhage.com/minigo/bit"
+)
+
+// Rules selects how a finished game is counted
+type Rules int
+
+const (
+	// AreaRules count each side's stones and the empty points they
+	// surround, as in Chinese rules
+	AreaRules Rules = iota
+	// TerritoryRules count the empty points each side surrounds
+	// and the stones it has captured, as in Japanese rules. Points
+	// surrounded by stones in seki count for neither side.
+	TerritoryRules
+)
+
+// Score is the count of a finished game
+type Score struct {
+	// Black and White are each side's points, before komi
+	Black, White int
+	// BlackTerritory and WhiteTerritory hold the empty points, and
+	// the points of dead stones, counted for each side
+	BlackTerritory, WhiteTerritory *bit.Vector
+	// Seki holds the stones found to be in seki
+	Seki *bit.Vector
+}
+
+// Score counts the game, removing the stones in dead as captured. dead
+// may be nil if no stones are dead. A region of empty points is
+// territory if it is bordered by one side's stones only.
+func (g *Game) Score(rules Rules, dead *bit.Vector) *Score {
+	n := g.Width * g.Height
+	if dead == nil {
+		dead = bit.NewVector(n)
+	}
+	b := *g.board
+	b.prev = nil
+	b.black = g.board.black.Copy().AndNot(dead)
+	b.white = g.board.white.Copy().AndNot(dead)
+	deadBlack := g.board.black.Copy().And(dead).Popcount()
+	deadWhite := g.board.white.Copy().And(dead).Popcount()
+
+	s := &Score{
+		BlackTerritory: bit.NewVector(n),
+		WhiteTerritory: bit.NewVector(n),
+		Seki:           b.seki(),
+	}
+	stones := b.black.Copy().Or(b.white)
+	for _, r := range b.components(stones.Copy().Not(), stones) {
+		edge := b.grow(r).AndNot(r)
+		if rules == TerritoryRules && edge.Copy().And(s.Seki).Popcount() > 0 {
+			continue
+		}
+		switch {
+		case edge.Popcount() == 0:
+		case edge.Copy().AndNot(b.black).Popcount() == 0:
+			s.BlackTerritory.Or(r)
+		case edge.Copy().AndNot(b.white).Popcount() == 0:
+			s.WhiteTerritory.Or(r)
+		}
+	}
+
+	s.Black, s.White = s.BlackTerritory.Popcount(), s.WhiteTerritory.Popcount()
+	if rules == TerritoryRules {
+		s.Black += b.blackPrisoners + deadWhite
+		s.White += b.whitePrisoners + deadBlack
+	} else {
+		s.Black += b.black.Popcount()
+		s.White += b.white.Popcount()
+	}
+	return s
+}
+
+// seki returns the stones in seki: chains with a liberty next to the
+// opponent's stones that neither side can play on without putting its
+// own stones in atari, and without capturing.
+func (b *boardState) seki() *bit.Vector {
+	out := bit.NewVector(b.white.Len())
+	empty := b.black.Copy().Or(b.white).Not()
+	for _, c := range []Color{Black, White} {
+		me, them := b.black, b.white
+		if c == White {
+			me, them = them, me
+		}
+		for _, chain := range b.components(me, me.Copy().Not()) {
+			shared := b.grow(chain).And(empty).And(b.grow(them))
+			if shared.Popcount() == 0 {
+				continue
+			}
+			seki := true
+			for i := 0; i < shared.Len() && seki; i++ {
+				if shared.At(i) {
+					seki = b.selfAtari(i, Black) && b.selfAtari(i, White)
+				}
+			}
+			if seki {
+				out.Or(chain)
+			}
+		}
+	}
+	return out
+}
+
+// selfAtari returns true if a move by c at idx is illegal, or captures
+// nothing and leaves the stone played with at most one liberty
+func (b *boardState) selfAtari(idx int, c Color) bool {
+	s := *b
+	s.prev = nil
+	s.toPlay = c
+	next, err := s.move(idx%b.g.Width, idx/b.g.Width)
+	if err != nil {
+		return true
+	}
+	if next.blackPrisoners != b.blackPrisoners || next.whitePrisoners != b.whitePrisoners {
+		return false
+	}
+	me := next.black
+	if c == White {
+		me = next.white
+	}
+	chain := next.floodFill(bit.NewVector(me.Len()).Set(idx), me.Copy().Not())
+	libs := next.grow(chain).AndNot(next.black).AndNot(next.white)
+	return libs.Popcount() <= 1
+}
+
+// Result formats the score as an SGF result, such as "B+3.5", after
+// giving white komi
+func (s *Score) Result(komi float64) string {
+	margin := float64(s.Black) - float64(s.White) - komi
+	switch {
+	case margin > 0:
+		return "B+" + strconv.FormatFloat(margin, 'f', -1, 64)
+	case margin < 0:
+		return "W+" + strconv.FormatFloat(-margin, 'f', -1, 64)
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,182 @@
+package game
+
+import "testing"
+
+func TestSeki(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		seki string
+	}{
+		{
+			"two shared liberties",
+			`
+0 + X O + + + + + +
+1 O X O + + + + + +
+2 + X O + + + + + +
+3 X X O + + + + + +
+4 O O O + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + X + + + + + + +
+1 O X + + + + + + +
+2 + X + + + + + + +
+3 X X + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			"one eye each",
+			`
+0 + X + O + O X + +
+1 X X X O O O X + +
+2 O O O X X X X + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + X + O + O + + +
+1 X X X O O O + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			"white can approach",
+			`
+0 + X O + + + + + +
+1 + X O + + + + + +
+2 + X O + + + + + +
+3 X X O + + + + + +
+4 O O O + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + + + + + + + + +
+1 + + + + + + + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+		{
+			"dame between living groups",
+			`
+0 + X + X O + O + +
+1 X X X X O O O O O
+2 X + X X O + + O +
+3 X X X X O O O O O
+4 + + + X O + + + +
+5 X X X X O + + + +
+6 + + + X O + + + +
+7 + + + X O + + + +
+8 + + + X O + + + +
+  0 1 2 3 4 5 6 7 8
+`, `
+0 + + + + + + + + +
+1 + + + + + + + + +
+2 + + + + + + + + +
+3 + + + + + + + + +
+4 + + + + + + + + +
+5 + + + + + + + + +
+6 + + + + + + + + +
+7 + + + + + + + + +
+8 + + + + + + + + +
+  0 1 2 3 4 5 6 7 8
+`,
+		},
+	}
+	for _, tc := range cases {
+		g := New(9)
+		g.board = board(g, tc.in)
+		want := board(g, tc.seki)
+		wantSeki := want.black.Copy().Or(want.white)
+		if s := g.Score(TerritoryRules, nil); !s.Seki.Equal(wantSeki) {
+			t.Errorf("%s: seki=\n%s\nwant=\n%s", tc.name,
+				&boardState{g: g, black: s.Seki.Copy().And(g.board.black), white: s.Seki.Copy().And(g.board.white)},
+				want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	g := New(9)
+	g.board = board(g, `
+0 + X + O + O X + +
+1 X X X O O O X + +
+2 O O O X X X X + +
+3 + + + X + + + + +
+4 O O O X + + + + +
+5 + + O X + + + + +
+6 + + O X + + + + +
+7 + + O X + + + + +
+8 + + O X + + + + +
+  0 1 2 3 4 5 6 7 8
+`)
+	g.board.blackPrisoners = 2
+
+	// Under territory rules, the eyes of the groups in seki count
+	// for neither side
+	s := g.Score(TerritoryRules, nil)
+	if s.BlackTerritory.At(0) || s.WhiteTerritory.At(4) {
+		t.Error("eye in seki counted as territory")
+	}
+	if s.Black != 36+2 || s.White != 8 {
+		t.Errorf("territory: black=%d white=%d", s.Black, s.White)
+	}
+	for komi, want := range map[float64]string{6.5: "B+23.5", 30: "0", 36.5: "W+6.5"} {
+		if r := s.Result(komi); r != want {
+			t.Errorf("Result(%v) = %q, want %q", komi, r, want)
+		}
+	}
+
+	// Under area rules, they count as usual
+	s = g.Score(AreaRules, nil)
+	if !s.BlackTerritory.At(0) || !s.WhiteTerritory.At(4) {
+		t.Error("eye in seki not counted under area rules")
+	}
+	if s.Black != 1+36+16 || s.White != 1+8+15 {
+		t.Errorf("area: black=%d white=%d", s.Black, s.White)
+	}
+
+	// Dead stones are removed and count as prisoners. Without
+	// white's outer stones, the groups are no longer in seki.
+	dead := g.board.white.Copy()
+	for i := 0; i < 9; i++ {
+		dead.Clear(i).Clear(9 + i)
+	}
+	s = g.Score(TerritoryRules, dead)
+	if s.Seki.Popcount() != 0 {
+		t.Errorf("seki: %v", s.Seki)
+	}
+	if s.Black != 1+21+36+2+10 || s.White != 1 {
+		t.Errorf("with dead stones: black=%d white=%d", s.Black, s.White)
+	}
+}
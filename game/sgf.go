@@ -0,0 +1,384 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+
+	"nelhage.com/minigo/bit"
+	"nelhage.com/minigo/sgf"
+)
+
+// FromSGF replays the principal variation of an SGF game tree,
+// honoring the board size in the root node's SZ property and any
+// AB/AW/AE setup properties along the way.
+func FromSGF(t *sgf.GameTree) (*Game, error) {
+	if len(t.Principal.Nodes) == 0 {
+		return nil, fmt.Errorf("sgf: empty game tree")
+	}
+	w, h, err := t.Principal.Nodes[0].BoardSize()
+	if err != nil {
+		return nil, err
+	}
+	g := NewRect(w, h)
+	if err := g.Info.read(&t.Principal.Nodes[0]); err != nil {
+		return nil, err
+	}
+	for {
+		for i := range t.Principal.Nodes {
+			if err := g.PlayNode(&t.Principal.Nodes[i]); err != nil {
+				return nil, err
+			}
+		}
+		if len(t.Children) == 0 {
+			break
+		}
+		t = t.Children[0]
+	}
+	return g, nil
+}
+
+// read fills in the information recorded in a root node
+func (i *Info) read(root *sgf.Node) error {
+	if v, ok := root.Value("PB"); ok {
+		i.Black = string(v)
+	}
+	if v, ok := root.Value("PW"); ok {
+		i.White = string(v)
+	}
+	if v, ok := root.Value("KM"); ok {
+		km, err := v.Real()
+		if err != nil {
+			return fmt.Errorf("sgf: bad KM: %q", string(v))
+		}
+		i.Komi = km
+	}
+	if v, ok := root.Value("RU"); ok {
+		i.Rules = string(v)
+	}
+	if v, ok := root.Value("RE"); ok {
+		i.Result = string(v)
+	}
+	if v, ok := root.Value("TM"); ok {
+		tm, err := v.Real()
+		if err != nil {
+			return fmt.Errorf("sgf: bad TM: %q", string(v))
+		}
+		i.TimeLimit = tm
+	}
+	if v, ok := root.Value("OT"); ok {
+		i.Overtime = string(v)
+	}
+	return nil
+}
+
+// props returns the root node properties that record i
+func (i *Info) props() []sgf.Property {
+	var out []sgf.Property
+	add := func(prop, val string) {
+		if val != "" {
+			out = append(out, sgf.Property{Prop: prop, Values: []sgf.PropValue{sgf.PropValue(val)}})
+		}
+	}
+	add("PB", i.Black)
+	add("PW", i.White)
+	if i.Komi != 0 {
+		add("KM", strconv.FormatFloat(i.Komi, 'f', -1, 64))
+	}
+	add("RU", i.Rules)
+	add("RE", i.Result)
+	if i.TimeLimit != 0 {
+		add("TM", strconv.FormatFloat(i.TimeLimit, 'f', -1, 64))
+	}
+	add("OT", i.Overtime)
+	return out
+}
+
+func (g *Game) point(v sgf.PropValue) (int, int, error) {
+	x, y, err := v.Point()
+	if err != nil {
+		return 0, 0, err
+	}
+	// FF[3] passes are written as `tt` on boards up to 19x19
+	if x == 19 && y == 19 && g.Width <= 19 && g.Height <= 19 {
+		return -1, -1, nil
+	}
+	return x, y, nil
+}
+
+// PlayNode applies the setup properties, move and annotations of an
+// SGF node to the game
+func (g *Game) PlayNode(n *sgf.Node) error {
+	start := g.board
+	var setup []sgf.Property
+	for _, p := range n.Props {
+		switch p.Prop {
+		case "AB", "AW", "AE":
+			setup = append(setup, p)
+		}
+	}
+	if setup != nil {
+		if err := g.setup(setup); err != nil {
+			return err
+		}
+	}
+	if v, ok := n.Value("PL"); ok {
+		g.setToPlay(v == "W")
+	}
+	for _, p := range n.Props {
+		var c Color
+		switch p.Prop {
+		case "B":
+			c = Black
+		case "W":
+			c = White
+		default:
+			continue
+		}
+		if len(p.Values) != 1 {
+			return fmt.Errorf("sgf: %s: expected one value", p.Prop)
+		}
+		x, y, err := g.point(p.Values[0])
+		if err != nil {
+			return err
+		}
+		g.setToPlay(c)
+		b, err := g.board.move(x, y)
+		if err != nil {
+			return fmt.Errorf("sgf: %s[%s]: %v", p.Prop, p.Values[0], err)
+		}
+		if b.timeLeft, err = readTimeLeft(n, c); err != nil {
+			return err
+		}
+		g.board = b
+	}
+
+	notes, err := n.Annotations()
+	if err != nil {
+		return fmt.Errorf("sgf: %v", err)
+	}
+	if !notes.Empty() {
+		g.annotate(notes, g.board != start)
+	}
+	return nil
+}
+
+// timeProps returns the names of the properties that record c's time
+// left
+func timeProps(c Color) (left, periods string) {
+	if c == White {
+		return "WL", "OW"
+	}
+	return "BL", "OB"
+}
+
+// readTimeLeft reads the time c had left after the move in n, if it
+// was recorded
+func readTimeLeft(n *sgf.Node, c Color) (*TimeLeft, error) {
+	left, periods := timeProps(c)
+	v, ok := n.Value(left)
+	if !ok {
+		return nil, nil
+	}
+	t := &TimeLeft{}
+	var err error
+	if t.Seconds, err = v.Real(); err != nil {
+		return nil, fmt.Errorf("sgf: bad %s: %q", left, string(v))
+	}
+	if v, ok := n.Value(periods); ok {
+		if t.Periods, err = v.Number(); err != nil {
+			return nil, fmt.Errorf("sgf: bad %s: %q", periods, string(v))
+		}
+	}
+	return t, nil
+}
+
+// timeLeftProps returns the properties that record the time c had
+// left after a move
+func timeLeftProps(t *TimeLeft, c Color) []sgf.Property {
+	left, periods := timeProps(c)
+	out := []sgf.Property{{
+		Prop:   left,
+		Values: []sgf.PropValue{sgf.PropValue(strconv.FormatFloat(t.Seconds, 'f', -1, 64))},
+	}}
+	if t.Periods > 0 {
+		out = append(out, sgf.Property{
+			Prop:   periods,
+			Values: []sgf.PropValue{sgf.PropValue(strconv.Itoa(t.Periods))},
+		})
+	}
+	return out
+}
+
+// annotate attaches annotations to the current position. If the
+// position was not produced by the annotated node, an unchanged
+// position is added to the game history to hold them, unless it is
+// the initial position and has no annotations yet, like setup.
+func (g *Game) annotate(notes *sgf.Annotations, fresh bool) {
+	b := *g.board
+	if !fresh && (g.board.prev != nil || g.board.notes != nil) {
+		b.prev = g.board
+		b.notesOnly = true
+	}
+	b.notes = notes
+	if notes.HasDimmed {
+		b.dimmed = notes.Dimmed
+	}
+	g.board = &b
+}
+
+// Annotations returns the SGF annotations and markup for the current
+// position, or nil if there are none
+func (g *Game) Annotations() *sgf.Annotations {
+	return g.board.notes
+}
+
+// Dimmed returns the points currently dimmed by the SGF DD property,
+// which applies until it is replaced
+func (g *Game) Dimmed() []sgf.Point {
+	return g.board.dimmed
+}
+
+// setToPlay replaces the current position with an otherwise-identical
+// one with `c` to move
+func (g *Game) setToPlay(c Color) {
+	if g.board.toPlay == c {
+		return
+	}
+	b := *g.board
+	b.toPlay = c
+	g.board = &b
+}
+
+// setup applies a node's setup properties. Setup in the initial
+// position modifies it in place; later setup produces a new position
+// so that it is preserved in the game history.
+func (g *Game) setup(props []sgf.Property) error {
+	b := *g.board
+	if g.board.prev != nil || g.board.setup {
+		b.prev = g.board
+		b.setup = true
+		b.lastX, b.lastY = -1, -1
+	}
+	b.white = b.white.Copy()
+	b.black = b.black.Copy()
+	for _, p := range props {
+		for _, v := range p.Values {
+			x0, y0, x1, y1, err := v.PointRange()
+			if err != nil {
+				return err
+			}
+			if x0 < 0 || x1 >= g.Width || y0 < 0 || y1 >= g.Height {
+				return fmt.Errorf("sgf: %s[%s]: %v", p.Prop, v, ErrOutOfBounds)
+			}
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					idx := y*g.Width + x
+					b.white.Clear(idx)
+					b.black.Clear(idx)
+					switch p.Prop {
+					case "AB":
+						b.black.Set(idx)
+					case "AW":
+						b.white.Set(idx)
+					}
+				}
+			}
+		}
+	}
+	g.board = &b
+	return nil
+}
+
+// SGF returns the game as an SGF game tree, with the initial position
+// recorded as setup properties in the root node followed by one node
+// per move.
+func (g *Game) SGF() *sgf.GameTree {
+	var history []*boardState
+	for b := g.board; b != nil; b = b.prev {
+		history = append(history, b)
+	}
+	first := history[len(history)-1]
+
+	root := sgf.Node{Props: []sgf.Property{
+		{Prop: "FF", Values: []sgf.PropValue{"4"}},
+		{Prop: "GM", Values: []sgf.PropValue{"1"}},
+		{Prop: "SZ", Values: []sgf.PropValue{sgf.SizeValue(g.Width, g.Height)}},
+	}}
+	root.Props = append(root.Props, g.Info.props()...)
+	if p := g.stonesProp("AB", first.black); p != nil {
+		root.Props = append(root.Props, *p)
+	}
+	if p := g.stonesProp("AW", first.white); p != nil {
+		root.Props = append(root.Props, *p)
+	}
+	if first.toPlay == White {
+		root.Props = append(root.Props, sgf.Property{
+			Prop: "PL", Values: []sgf.PropValue{"W"}})
+	}
+	if first.notes != nil {
+		root.Props = append(root.Props, first.notes.Props()...)
+	}
+
+	t := &sgf.GameTree{}
+	t.Principal.Nodes = append(t.Principal.Nodes, root)
+	for i := len(history) - 2; i >= 0; i-- {
+		b := history[i]
+		var n sgf.Node
+		switch {
+		case b.notesOnly:
+			// The node holds only annotations
+		case b.setup:
+			n = g.setupNode(b)
+		default:
+			prop := "B"
+			if b.prev.toPlay == White {
+				prop = "W"
+			}
+			n.Props = []sgf.Property{{
+				Prop:   prop,
+				Values: []sgf.PropValue{sgf.PointValue(b.lastX, b.lastY)},
+			}}
+			if b.timeLeft != nil {
+				n.Props = append(n.Props, timeLeftProps(b.timeLeft, b.prev.toPlay)...)
+			}
+		}
+		if b.notes != nil {
+			n.Props = append(n.Props, b.notes.Props()...)
+		}
+		t.Principal.Nodes = append(t.Principal.Nodes, n)
+	}
+	return t
+}
+
+func (g *Game) stonesProp(prop string, stones *bit.Vector) *sgf.Property {
+	if stones.Popcount() == 0 {
+		return nil
+	}
+	p := &sgf.Property{Prop: prop}
+	for i := 0; i < stones.Len(); i++ {
+		if stones.At(i) {
+			p.Values = append(p.Values, sgf.PointValue(i%g.Width, i/g.Width))
+		}
+	}
+	return p
+}
+
+// setupNode returns a node containing the setup properties that
+// transform b.prev into b
+func (g *Game) setupNode(b *boardState) sgf.Node {
+	var n sgf.Node
+	added := b.black.Copy().AndNot(b.prev.black)
+	if p := g.stonesProp("AB", added); p != nil {
+		n.Props = append(n.Props, *p)
+	}
+	added = b.white.Copy().AndNot(b.prev.white)
+	if p := g.stonesProp("AW", added); p != nil {
+		n.Props = append(n.Props, *p)
+	}
+	removed := b.prev.black.Copy().Or(b.prev.white).
+		AndNot(b.black).AndNot(b.white)
+	if p := g.stonesProp("AE", removed); p != nil {
+		n.Props = append(n.Props, *p)
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+package game
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/sgf"
+)
+
+func parse(t *testing.T, in string) *sgf.GameTree {
+	c, err := sgf.ParseSGF(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return c.Trees[0]
+}
+
+func TestFromSGF(t *testing.T) {
+	g, err := FromSGF(parse(t, `
+(;FF[4]GM[1]SZ[7:4]AB[fb][gc]AW[gb]
+ ;B[ga]
+ (;W[ad];B[])
+ (;W[bb]))`))
+	if err != nil {
+		t.Fatal("FromSGF:", err)
+	}
+	if g.Width != 7 || g.Height != 4 {
+		t.Fatalf("size %dx%d", g.Width, g.Height)
+	}
+	if _, ok := g.At(6, 1); ok {
+		t.Errorf("(6,1) not captured:\n%s", g.board)
+	}
+	if c, ok := g.At(0, 3); !ok || c != White {
+		t.Errorf("At(0,3) = (%v,%v)", c, ok)
+	}
+	if g.board.passes != 1 || g.ToPlay() != White {
+		t.Errorf("passes=%d toPlay=%v", g.board.passes, g.ToPlay())
+	}
+
+	if _, err := FromSGF(parse(t, "(;SZ[5];B[fa])")); err == nil {
+		t.Errorf("out of bounds move accepted")
+	}
+}
+
+func TestSGFRoundTrip(t *testing.T) {
+	g := NewRect(13, 5)
+	g.Info = Info{
+		Black: "Honinbo Shusaku", White: "Gennan Inseki", Komi: 6.5, Rules: "Japanese",
+		TimeLimit: 600, Overtime: "5x30 byo-yomi",
+	}
+	moves := []struct{ x, y int }{
+		{3, 2}, {9, 2}, {12, 4}, {-1, -1}, {0, 0},
+	}
+	for i, m := range moves {
+		if err := g.Move(m.x, m.y); err != nil {
+			t.Fatalf("Move(%d,%d): %v", m.x, m.y, err)
+		}
+		g.SetTimeLeft(TimeLeft{Seconds: 600 - float64(i), Periods: i % 2 * 5})
+	}
+	tree := g.SGF()
+	if v, _ := tree.Principal.Nodes[0].Value("SZ"); v != "13:5" {
+		t.Errorf("SZ=%q", v)
+	}
+	if n := tree.Principal.Nodes[2]; n.Get("WL") == nil || n.Get("OW") == nil {
+		t.Errorf("second move: %v", n.Props)
+	}
+	if n := tree.Principal.Nodes[3]; n.Get("BL") == nil || n.Get("OB") != nil {
+		t.Errorf("third move: %v", n.Props)
+	}
+	g2, err := FromSGF(tree)
+	if err != nil {
+		t.Fatal("FromSGF:", err)
+	}
+	if !g2.board.white.Equal(g.board.white) ||
+		!g2.board.black.Equal(g.board.black) ||
+		g2.ToPlay() != g.ToPlay() {
+		t.Errorf("want:\n%s\ngot:\n%s", g.board, g2.board)
+	}
+	if g2.Info != g.Info {
+		t.Errorf("Info = %+v", g2.Info)
+	}
+	if tl, ok := g2.TimeLeft(); !ok || tl != (TimeLeft{Seconds: 596}) {
+		t.Errorf("TimeLeft() = %+v, %v", tl, ok)
+	}
+	if !reflect.DeepEqual(g2.SGF(), tree) {
+		t.Errorf("SGF() not stable")
+	}
+}
+
+func TestSGFKoAfterComment(t *testing.T) {
+	const start = `(;SZ[5]AB[ba][ab][bc][cb]AW[ca][db][cc]PL[W]
+ ;W[bb]C[captures]
+ ;C[note]`
+	g, err := FromSGF(parse(t, start+")"))
+	if err != nil {
+		t.Fatal("FromSGF:", err)
+	}
+	if x, y, c, ok := g.LastMove(); !ok || x != 1 || y != 1 || c != White {
+		t.Errorf("LastMove() = (%d,%d,%v,%v)", x, y, c, ok)
+	}
+	if x, y, ok := g.KoPoint(); !ok || x != 2 || y != 1 {
+		t.Errorf("KoPoint() = (%d,%d,%v)", x, y, ok)
+	}
+	if err := g.Move(2, 1); err != ErrKo {
+		t.Errorf("retake: %v", err)
+	}
+	if _, err := FromSGF(parse(t, start+";B[cb])")); err == nil {
+		t.Error("ko retake after a comment loaded")
+	}
+	if !reflect.DeepEqual(g.SGF().Principal.Nodes[2].Props, parse(t, start+")").Principal.Nodes[2].Props) {
+		t.Errorf("comment node: %v", g.SGF().Principal.Nodes[2])
+	}
+}
+
+func TestSGFCommentNode(t *testing.T) {
+	for _, in := range []string{
+		"(;FF[4]GM[1]SZ[9];B[aa];C[note only];W[bb])",
+		"(;FF[4]GM[1]SZ[9];B[aa]C[a];C[note only];W[bb])",
+	} {
+		g, err := FromSGF(parse(t, in))
+		if err != nil {
+			t.Fatal("FromSGF:", err)
+		}
+		var buf bytes.Buffer
+		if err := sgf.WriteSGF(&buf, &sgf.Collection{Trees: []*sgf.GameTree{g.SGF()}}); err != nil {
+			t.Fatal(err)
+		}
+		if out := strings.TrimSpace(buf.String()); out != in {
+			t.Errorf("%s: SGF() = %s", in, out)
+		}
+	}
+}
+
+func TestSGFAnnotations(t *testing.T) {
+	tree := parse(t, `(;SZ[9]C[start]
+ ;B[cc]TE[1]DD[aa:bb]
+ ;W[dd]C[hmm]
+ ;C[still thinking]
+ ;B[ee]DD[])`)
+	g, err := FromSGF(tree)
+	if err != nil {
+		t.Fatal("FromSGF:", err)
+	}
+	if a := g.Annotations(); a == nil || !a.HasDimmed {
+		t.Errorf("annotations: %+v", a)
+	}
+	if len(g.Dimmed()) != 0 {
+		t.Errorf("dimmed: %v", g.Dimmed())
+	}
+	var comments []string
+	var dimmed []int
+	for b := g.board; b != nil; b = b.prev {
+		if b.notes != nil {
+			comments = append([]string{b.notes.Comment}, comments...)
+		}
+		dimmed = append([]int{len(b.dimmed)}, dimmed...)
+	}
+	want := []string{"start", "", "hmm", "still thinking", ""}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("comments %q", comments)
+	}
+	if !reflect.DeepEqual(dimmed, []int{0, 4, 4, 4, 0}) {
+		t.Errorf("dimmed %v", dimmed)
+	}
+
+	out := g.SGF()
+	if len(out.Principal.Nodes) != 5 {
+		t.Fatalf("exported %d nodes", len(out.Principal.Nodes))
+	}
+	if v, _ := out.Principal.Nodes[3].Value("C"); v != "still thinking" {
+		t.Errorf("exported C=%q", v)
+	}
+	if v, _ := out.Principal.Nodes[1].Value("TE"); v != "1" {
+		t.Errorf("exported TE=%q", v)
+	}
+}
@@ -0,0 +1,300 @@
+package game
+
+import (
+	"sync"
+
+	"nelhage.com/minigo/bit"
+)
+
+// Symmetry is one of the eight rotations and reflections of a board.
+// Coordinates are as in Move, with y increasing down the board.
+type Symmetry uint8
+
+const (
+	// Identity leaves the board unchanged
+	Identity Symmetry = iota
+	// Rotate90 rotates the board a quarter turn clockwise
+	Rotate90
+	// Rotate180 rotates the board a half turn
+	Rotate180
+	// Rotate270 rotates the board a quarter turn counterclockwise
+	Rotate270
+	// FlipHorizontal mirrors the board left to right
+	FlipHorizontal
+	// FlipVertical mirrors the board top to bottom
+	FlipVertical
+	// Transpose reflects the board in the diagonal through the
+	// top-left corner
+	Transpose
+	// AntiTranspose reflects the board in the diagonal through the
+	// top-right corner
+	AntiTranspose
+)
+
+// Symmetries lists all the symmetries of a square board
+var Symmetries = []Symmetry{
+	Identity, Rotate90, Rotate180, Rotate270,
+	FlipHorizontal, FlipVertical, Transpose, AntiTranspose,
+}
+
+var symmetryNames = []string{
+	"identity", "rotate90", "rotate180", "rotate270",
+	"flip_horizontal", "flip_vertical", "transpose", "anti_transpose",
+}
+
+func (s Symmetry) String() string {
+	return symmetryNames[s]
+}
+
+// Square returns true if s exchanges the axes, and so only applies to
+// square boards
+func (s Symmetry) Square() bool {
+	switch s {
+	case Rotate90, Rotate270, Transpose, AntiTranspose:
+		return true
+	}
+	return false
+}
+
+// Inverse returns the symmetry that undoes s
+func (s Symmetry) Inverse() Symmetry {
+	switch s {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	}
+	return s
+}
+
+// Point maps the point (x,y) on a board of the given dimensions. A
+// pass at -1,-1 is left alone.
+func (s Symmetry) Point(width, height, x, y int) (int, int) {
+	if x < 0 {
+		return x, y
+	}
+	mx, my := width-1-x, height-1-y
+	switch s {
+	case Rotate90:
+		return height - 1 - y, x
+	case Rotate180:
+		return mx, my
+	case Rotate270:
+		return y, width - 1 - x
+	case FlipHorizontal:
+		return mx, y
+	case FlipVertical:
+		return x, my
+	case Transpose:
+		return y, x
+	case AntiTranspose:
+		return height - 1 - y, width - 1 - x
+	}
+	return x, y
+}
+
+// symmetryMasks holds the masks used to permute the bits of a board
+// of one size
+type symmetryMasks struct {
+	cols, rows []*bit.Vector
+	// diags[d] holds the points with x-y = d-(size-1), on square
+	// boards
+	diags []*bit.Vector
+}
+
+type boardSize struct {
+	width, height int
+}
+
+var symmetryCache sync.Map
+
+func masksFor(width, height int) *symmetryMasks {
+	key := boardSize{width, height}
+	if m, ok := symmetryCache.Load(key); ok {
+		return m.(*symmetryMasks)
+	}
+	n := width * height
+	m := &symmetryMasks{}
+	for x := 0; x < width; x++ {
+		v := bit.NewVector(n)
+		for y := 0; y < height; y++ {
+			v.Set(y*width + x)
+		}
+		m.cols = append(m.cols, v)
+	}
+	for y := 0; y < height; y++ {
+		v := bit.NewVector(n)
+		for x := 0; x < width; x++ {
+			v.Set(y*width + x)
+		}
+		m.rows = append(m.rows, v)
+	}
+	if width == height {
+		for d := -(width - 1); d < width; d++ {
+			v := bit.NewVector(n)
+			for y := 0; y < height; y++ {
+				if x := y + d; x >= 0 && x < width {
+					v.Set(y*width + x)
+				}
+			}
+			m.diags = append(m.diags, v)
+		}
+	}
+	actual, _ := symmetryCache.LoadOrStore(key, m)
+	return actual.(*symmetryMasks)
+}
+
+// shiftMasked gathers the bits of v selected by mask into out, moved
+// delta positions towards higher indexes. part is scratch space.
+func shiftMasked(out, part, v, mask *bit.Vector, delta int) {
+	part.AndNot(part).Or(v).And(mask)
+	if delta > 0 {
+		part.Rsh(uint(delta))
+	} else if delta < 0 {
+		part.Lsh(uint(-delta))
+	}
+	out.Or(part)
+}
+
+func (m *symmetryMasks) flipHorizontal(v *bit.Vector) *bit.Vector {
+	out, part := bit.NewVector(v.Len()), bit.NewVector(v.Len())
+	w := len(m.cols)
+	for x, col := range m.cols {
+		shiftMasked(out, part, v, col, w-1-2*x)
+	}
+	return out
+}
+
+func (m *symmetryMasks) flipVertical(v *bit.Vector) *bit.Vector {
+	out, part := bit.NewVector(v.Len()), bit.NewVector(v.Len())
+	w, h := len(m.cols), len(m.rows)
+	for y, row := range m.rows {
+		shiftMasked(out, part, v, row, (h-1-2*y)*w)
+	}
+	return out
+}
+
+func (m *symmetryMasks) transpose(v *bit.Vector) *bit.Vector {
+	out, part := bit.NewVector(v.Len()), bit.NewVector(v.Len())
+	n := len(m.cols)
+	for i, diag := range m.diags {
+		// (x,y) moves from y*n+x to x*n+y
+		shiftMasked(out, part, v, diag, (i-(n-1))*(n-1))
+	}
+	return out
+}
+
+// Vector applies s to a bit vector holding a board of the given
+// dimensions in row-major order, returning a new vector. It panics if
+// s exchanges the axes of a board that is not square.
+func (s Symmetry) Vector(width, height int, v *bit.Vector) *bit.Vector {
+	if s.Square() && width != height {
+		panic("game: symmetry requires a square board")
+	}
+	m := masksFor(width, height)
+	switch s {
+	case Rotate90:
+		return m.flipHorizontal(m.transpose(v))
+	case Rotate180:
+		return m.flipHorizontal(m.flipVertical(v))
+	case Rotate270:
+		return m.flipVertical(m.transpose(v))
+	case FlipHorizontal:
+		return m.flipHorizontal(v)
+	case FlipVertical:
+		return m.flipVertical(v)
+	case Transpose:
+		return m.transpose(v)
+	case AntiTranspose:
+		return m.flipHorizontal(m.flipVertical(m.transpose(v)))
+	}
+	return v.Copy()
+}
+
+// Position is the arrangement of stones on a board, and the player to
+// play
+type Position struct {
+	Width, Height int
+	Black, White  *bit.Vector
+	ToPlay        Color
+}
+
+// Position returns the current position of the game. The position
+// does not share storage with the game.
+func (g *Game) Position() *Position {
+	return &Position{
+		Width:  g.Width,
+		Height: g.Height,
+		Black:  g.board.black.Copy(),
+		White:  g.board.white.Copy(),
+		ToPlay: g.board.toPlay,
+	}
+}
+
+// FromPosition returns a new game starting from a position
+func FromPosition(p *Position) *Game {
+	g := NewRect(p.Width, p.Height)
+	g.board.black = p.Black.Copy()
+	g.board.white = p.White.Copy()
+	g.board.toPlay = p.ToPlay
+	return g
+}
+
+// At returns the color of the stone at (x,y), if there is one
+func (p *Position) At(x, y int) (Color, bool) {
+	i := y*p.Width + x
+	switch {
+	case p.Black.At(i):
+		return Black, true
+	case p.White.At(i):
+		return White, true
+	}
+	return Black, false
+}
+
+// Transform returns the position transformed by s, which must not
+// exchange the axes of a board that is not square
+func (p *Position) Transform(s Symmetry) *Position {
+	w, h := p.Width, p.Height
+	if s.Square() {
+		w, h = h, w
+	}
+	return &Position{
+		Width:  w,
+		Height: h,
+		Black:  s.Vector(p.Width, p.Height, p.Black),
+		White:  s.Vector(p.Width, p.Height, p.White),
+		ToPlay: p.ToPlay,
+	}
+}
+
+// Equal returns true if p and q hold the same stones on boards of the
+// same dimensions, with the same player to play
+func (p *Position) Equal(q *Position) bool {
+	return p.Width == q.Width && p.Height == q.Height && p.ToPlay == q.ToPlay &&
+		p.Black.Equal(q.Black) && p.White.Equal(q.White)
+}
+
+func (p *Position) compare(q *Position) int {
+	if c := p.Black.Compare(q.Black); c != 0 {
+		return c
+	}
+	return p.White.Compare(q.White)
+}
+
+// Canonical returns the canonical form of p, which is the same for
+// all positions related by a symmetry, along with the symmetry that
+// transforms p into it. Rectangular boards are only reflected and
+// rotated a half turn.
+func (p *Position) Canonical() (*Position, Symmetry) {
+	best, bestSym := p, Identity
+	for _, s := range Symmetries[1:] {
+		if s.Square() && p.Width != p.Height {
+			continue
+		}
+		if q := p.Transform(s); q.compare(best) < 0 {
+			best, bestSym = q, s
+		}
+	}
+	return best, bestSym
+}
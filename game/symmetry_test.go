@@ -0,0 +1,105 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"nelhage.com/minigo/bit"
+)
+
+func randomVector(r *rand.Rand, n int) *bit.Vector {
+	v := bit.NewVector(n)
+	for i := 0; i < n; i++ {
+		if r.Intn(3) == 0 {
+			v.Set(i)
+		}
+	}
+	return v
+}
+
+func TestSymmetryVector(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, dim := range [][2]int{{1, 1}, {5, 5}, {9, 9}, {19, 19}, {7, 4}, {13, 9}} {
+		w, h := dim[0], dim[1]
+		for _, s := range Symmetries {
+			if s.Square() && w != h {
+				continue
+			}
+			v := randomVector(r, w*h)
+			got := s.Vector(w, h, v)
+			ow, oh := w, h
+			if s.Square() {
+				ow, oh = h, w
+			}
+			want := bit.NewVector(w * h)
+			for i := 0; i < w*h; i++ {
+				if v.At(i) {
+					x, y := s.Point(w, h, i%w, i/w)
+					want.Set(y*ow + x)
+				}
+			}
+			if !got.Equal(want) {
+				t.Errorf("%dx%d %s: vector and point transforms disagree", w, h, s)
+			}
+			if back := s.Inverse().Vector(ow, oh, got); !back.Equal(v) {
+				t.Errorf("%dx%d %s: inverse does not round-trip", w, h, s)
+			}
+		}
+	}
+}
+
+func TestSymmetryPoint(t *testing.T) {
+	cases := []struct {
+		s    Symmetry
+		x, y int
+	}{
+		{Identity, 1, 0},
+		{Rotate90, 4, 1},
+		{Rotate180, 3, 4},
+		{Rotate270, 0, 3},
+		{FlipHorizontal, 3, 0},
+		{FlipVertical, 1, 4},
+		{Transpose, 0, 1},
+		{AntiTranspose, 4, 3},
+	}
+	for _, tc := range cases {
+		if x, y := tc.s.Point(5, 5, 1, 0); x != tc.x || y != tc.y {
+			t.Errorf("%s: (1,0) -> (%d,%d), want (%d,%d)", tc.s, x, y, tc.x, tc.y)
+		}
+	}
+	if x, y := Rotate90.Point(5, 5, -1, -1); x != -1 || y != -1 {
+		t.Errorf("pass moved to (%d,%d)", x, y)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	g := game(5, `
+5 + + + + +
+4 + X O + +
+3 + + X + +
+2 + + + + +
+1 + + + + O
+`, Black)
+	p := g.Position()
+	want, _ := p.Canonical()
+	for _, s := range Symmetries {
+		q := p.Transform(s)
+		c, cs := q.Canonical()
+		if !c.Equal(want) {
+			t.Errorf("%s: canonical forms differ", s)
+		}
+		if !q.Transform(cs).Equal(c) {
+			t.Errorf("%s: canonical symmetry %s does not produce canonical form", s, cs)
+		}
+	}
+}
+
+func BenchmarkCanonical(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	p := &Position{Width: 19, Height: 19, Black: randomVector(r, 361), White: bit.NewVector(361)}
+	p.White = randomVector(r, 361).AndNot(p.Black)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Canonical()
+	}
+}
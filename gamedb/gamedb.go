@@ -0,0 +1,369 @@
+// Package gamedb indexes collections of game records by the positions
+// they reach, and answers queries for games that reach a given
+// position or contain a local pattern, along with statistics about
+// how play continued.
+//
+// Positions, including the player to play, are identified by Zobrist
+// hashes that are canonical over the eight symmetries of the board
+// and over swapping the colors of the stones, so a query finds games
+// that reach the same position in any orientation, and with either
+// color playing either side. Only square boards are indexed.
+package gamedb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/records"
+	"nelhage.com/minigo/sgf"
+)
+
+// ErrNotSquare is returned for games on rectangular boards
+var ErrNotSquare = errors.New("gamedb: only square boards are supported")
+
+// formatVersion is bumped whenever the index file format, or the
+// hash function, changes
+const formatVersion = 3
+
+// Game describes an indexed game
+type Game struct {
+	// Path is the file the game was read from, and Index the
+	// position of the game tree within the file's collection
+	Path  string
+	Index int
+
+	Size   int
+	Black  string
+	White  string
+	Date   string
+	Result string
+
+	// Record is the main line of the game, in SGF, with only the
+	// properties needed to replay it
+	Record string
+	// MaxStones is the most black and white stones, in that order,
+	// on the board at any point of the main line
+	MaxStones [2]int
+}
+
+// Winner returns the color that won the game, and false if the game
+// has no winner
+func (g *Game) Winner() (game.Color, bool) {
+	switch {
+	case strings.HasPrefix(g.Result, "B+"):
+		return game.Black, true
+	case strings.HasPrefix(g.Result, "W+"):
+		return game.White, true
+	}
+	return game.Black, false
+}
+
+// occurrence records that a game reached an indexed position
+type occurrence struct {
+	Game int32
+	// Moves is the number of moves played to reach the position
+	Moves int32
+	// Transform maps the game's board to the canonical one
+	Transform transform
+	// NextX, NextY and NextColor are the following move in the
+	// game's orientation. NextColor is empty if the game ended.
+	NextX, NextY int16
+	NextColor    int8
+}
+
+func (o *occurrence) next() move {
+	return move{int(o.NextX), int(o.NextY), o.NextColor, o.NextColor != empty}
+}
+
+type hashedOccurrence struct {
+	hash uint64
+	occ  occurrence
+}
+
+// DB is an index of games by position. Its methods are not safe for
+// concurrent use while games are being added.
+type DB struct {
+	Games     []*Game
+	positions map[uint64][]occurrence
+}
+
+// New returns an empty database
+func New() *DB {
+	return &DB{positions: make(map[uint64][]occurrence)}
+}
+
+// file is the on-disk form of a DB
+type file struct {
+	Version   int
+	Games     []*Game
+	Positions map[uint64][]occurrence
+}
+
+// Load reads a database written by Save
+func Load(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var data file
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if data.Version != formatVersion {
+		return nil, fmt.Errorf("%s: unsupported index version %d", path, data.Version)
+	}
+	if data.Positions == nil {
+		data.Positions = make(map[uint64][]occurrence)
+	}
+	return &DB{Games: data.Games, positions: data.Positions}, nil
+}
+
+// Save writes the database to a file, replacing it atomically
+func (db *DB) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(&file{formatVersion, db.Games, db.positions})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// IndexFile adds every game in a file to the database. SGF files are
+// read, as are the formats understood by package records.
+func (db *DB) IndexFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var c *sgf.Collection
+	if parse := records.ForFile(path); parse != nil {
+		c, err = parse(f, records.Options{})
+	} else {
+		c, err = sgf.ParseSGF(f)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for i, t := range c.Trees {
+		if err := db.Add(path, i, t); err != nil {
+			return fmt.Errorf("%s: game %d: %v", path, i+1, err)
+		}
+	}
+	return nil
+}
+
+// IndexDir adds every game record under a directory to the database,
+// returning the errors for any files that could not be indexed
+func (db *DB) IndexDir(dir string) ([]error, error) {
+	var errs []error
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".sgf" && records.ForFile(path) == nil {
+			return nil
+		}
+		if err := db.IndexFile(path); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	return errs, err
+}
+
+// Add indexes the main line of a game tree. path and index identify
+// its source.
+func (db *DB) Add(path string, index int, t *sgf.GameTree) error {
+	line := mainLine(t)
+	if len(line.Principal.Nodes) == 0 {
+		return errors.New("empty game tree")
+	}
+	root := &line.Principal.Nodes[0]
+	w, h, err := root.BoardSize()
+	if err != nil {
+		return err
+	}
+	if w != h {
+		return ErrNotSquare
+	}
+	var record bytes.Buffer
+	if err := sgf.WriteSGF(&record, &sgf.Collection{Trees: []*sgf.GameTree{line}}); err != nil {
+		return err
+	}
+	info := func(prop string) string {
+		v, _ := t.Principal.Nodes[0].Value(prop)
+		return string(v)
+	}
+	g := &Game{
+		Path:   path,
+		Index:  index,
+		Size:   w,
+		Black:  info("PB"),
+		White:  info("PW"),
+		Date:   info("DT"),
+		Result: info("RE"),
+		Record: record.String(),
+	}
+
+	id := int32(len(db.Games))
+	var occs []hashedOccurrence
+	seen := make(map[uint64]bool)
+	err = replay(line, func(board []int8, toPlay int8, moves int, next move) bool {
+		stones := countStones(board)
+		for i, n := range stones {
+			if n > g.MaxStones[i] {
+				g.MaxStones[i] = n
+			}
+		}
+		hash, t := canonical(w, board, toPlay, next)
+		if seen[hash] {
+			return true
+		}
+		seen[hash] = true
+		o := occurrence{Game: id, Moves: int32(moves), Transform: t}
+		if next.ok {
+			o.NextX, o.NextY, o.NextColor = int16(next.x), int16(next.y), next.color
+		}
+		occs = append(occs, hashedOccurrence{hash, o})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	db.Games = append(db.Games, g)
+	for _, o := range occs {
+		db.positions[o.hash] = append(db.positions[o.hash], o.occ)
+	}
+	return nil
+}
+
+// replayProps are the properties kept in a Game's Record
+var replayProps = map[string]bool{
+	"SZ": true, "B": true, "W": true,
+	"AB": true, "AW": true, "AE": true, "PL": true,
+}
+
+// mainLine returns the main line of t, keeping only the properties
+// needed to replay it
+func mainLine(t *sgf.GameTree) *sgf.GameTree {
+	out := &sgf.GameTree{}
+	for ; t != nil; t = firstChild(t) {
+		for _, n := range t.Principal.Nodes {
+			var keep sgf.Node
+			for _, p := range n.Props {
+				if replayProps[p.Prop] {
+					keep.Props = append(keep.Props, p)
+				}
+			}
+			out.Principal.Nodes = append(out.Principal.Nodes, keep)
+		}
+	}
+	return out
+}
+
+func firstChild(t *sgf.GameTree) *sgf.GameTree {
+	if len(t.Children) == 0 {
+		return nil
+	}
+	return t.Children[0]
+}
+
+// move is a move in a game. A pass is at -1,-1.
+type move struct {
+	x, y  int
+	color int8
+	ok    bool
+}
+
+// replay plays through a main line, calling fn with each position
+// reached, the player to play, the number of moves played to reach it
+// and the move that followed it, until fn returns false
+func replay(t *sgf.GameTree, fn func(board []int8, toPlay int8, moves int, next move) bool) error {
+	nodes := t.Principal.Nodes
+	w, h, err := nodes[0].BoardSize()
+	if err != nil {
+		return err
+	}
+	g := game.NewRect(w, h)
+	if err := g.PlayNode(&nodes[0]); err != nil {
+		return err
+	}
+	moves := 0
+	for i := range nodes {
+		board, toPlay := snapshot(g), colorOf(g.ToPlay())
+		var next move
+		if i+1 < len(nodes) {
+			n := &nodes[i+1]
+			if err := g.PlayNode(n); err != nil {
+				return err
+			}
+			if n.Get("B") != nil || n.Get("W") != nil {
+				next.x, next.y, next.color, next.ok = lastMove(g)
+			}
+		}
+		if !fn(board, toPlay, moves, next) {
+			return nil
+		}
+		if next.ok {
+			moves++
+		}
+	}
+	return nil
+}
+
+func lastMove(g *game.Game) (int, int, int8, bool) {
+	x, y, c, ok := g.LastMove()
+	return x, y, colorOf(c), ok
+}
+
+func colorOf(c game.Color) int8 {
+	if c == game.White {
+		return white
+	}
+	return black
+}
+
+// countStones returns the number of black and white stones on a board,
+// or in a pattern
+func countStones(board []int8) [2]int {
+	var n [2]int
+	for _, c := range board {
+		switch c {
+		case black:
+			n[0]++
+		case white:
+			n[1]++
+		}
+	}
+	return n
+}
+
+// snapshot returns the stones on the board in row-major order
+func snapshot(g *game.Game) []int8 {
+	board := make([]int8, g.Width*g.Height)
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			if c, ok := g.At(x, y); ok {
+				board[y*g.Width+x] = colorOf(c)
+			}
+		}
+	}
+	return board
+}
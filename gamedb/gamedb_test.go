@@ -0,0 +1,164 @@
+package gamedb
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+var games = []string{
+	`(;SZ[9]PB[alice]PW[bob]RE[B+R];B[cc];W[gg];B[cg];W[gc];B[ee])`,
+	// The first game mirrored left to right, with the colors swapped
+	`(;SZ[9]PB[carol]PW[dave]RE[W+2]PL[W];W[gc];B[cg];W[gg];B[cc];W[ee])`,
+	`(;SZ[9]PB[erin]PW[frank]RE[W+R];B[cc];W[gg];B[cg];W[gc];B[dc]` +
+		`(;W[ee])(;W[dd]))`,
+	`(;SZ[13];B[cc];W[gg];B[cg];W[gc];B[dc])`,
+}
+
+func testDB(t *testing.T) *DB {
+	db := New()
+	for i, s := range games {
+		c, err := sgf.ParseSGF(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Add("test.sgf", i, c.Trees[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func play(t *testing.T, size int, moves ...string) *game.Game {
+	g := game.New(size)
+	for _, m := range moves {
+		x, y, err := sgf.PropValue(m).Point()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Move(x, y); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	return g
+}
+
+func TestTransform(t *testing.T) {
+	const n = 5
+	for tr := transform(0); tr < numTransform; tr++ {
+		seen := make(map[[2]int]bool)
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				tx, ty := tr.point(n, x, y)
+				seen[[2]int{tx, ty}] = true
+				if ix, iy := tr.inverse().point(n, tx, ty); ix != x || iy != y {
+					t.Errorf("%d: (%d,%d) -> (%d,%d) -> (%d,%d)", tr, x, y, tx, ty, ix, iy)
+				}
+			}
+		}
+		if len(seen) != n*n {
+			t.Errorf("%d: not a permutation", tr)
+		}
+	}
+}
+
+func checkResult(t *testing.T, res *Result, games int, want string) {
+	t.Helper()
+	if len(res.Matches) != games {
+		t.Errorf("matches: got %d, want %d", len(res.Matches), games)
+	}
+	var got []string
+	for _, c := range res.Continuations {
+		color := "B"
+		if c.Color == game.White {
+			color = "W"
+		}
+		got = append(got, color+string(sgf.PointValue(c.Move.X, c.Move.Y))+
+			strings.Repeat("+", c.Wins)+strings.Repeat("-", c.Losses))
+	}
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("continuations: got %q, want %q", s, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := testDB(t)
+	checkResult(t, mustSearch(t, db, play(t, 9, "cc", "gg", "cg", "gc")), 3, "Bee++ Bdc-")
+	// The same position, transposed
+	checkResult(t, mustSearch(t, db, play(t, 9, "cc", "gg", "gc", "cg")), 3, "Bee++ Bcd-")
+	// Only the variation's main line is indexed
+	checkResult(t, mustSearch(t, db, play(t, 9, "cc", "gg", "cg", "gc", "dc")), 1, "Wee+")
+	checkResult(t, mustSearch(t, db, play(t, 9, "cc", "gg", "cg", "gc", "dc", "dd")), 0, "")
+	checkResult(t, mustSearch(t, db, play(t, 13, "cc", "gg", "cg", "gc")), 1, "Bdc")
+	res := mustSearch(t, db, play(t, 9))
+	if len(res.Continuations) != 1 || res.Continuations[0].Count != 3 {
+		t.Errorf("empty board: %+v", res.Continuations)
+	}
+}
+
+func mustSearch(t *testing.T, db *DB, g *game.Game) *Result {
+	t.Helper()
+	res, err := db.Search(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestSearchPattern(t *testing.T) {
+	db := testDB(t)
+	res, err := db.SearchPattern(&Pattern{Rows: []string{
+		"...",
+		"...",
+		"..X",
+	}, Corner: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, res, 4, "Wgg+--")
+	for _, m := range res.Matches {
+		if m.Moves != 1 {
+			t.Errorf("%s: matched after %d moves", m.Game.Black, m.Moves)
+		}
+	}
+
+	res, err = db.SearchPattern(&Pattern{Rows: []string{"XX"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, res, 2, "Wcc+")
+
+	// No game ever has four stones of one color, so none need be
+	// replayed
+	for _, gm := range db.Games {
+		gm.Record = "not SGF"
+	}
+	res, err = db.SearchPattern(&Pattern{Rows: []string{"XXXX"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, res, 0, "")
+
+	if _, err := db.SearchPattern(&Pattern{Rows: []string{"X", "XO"}}); err == nil {
+		t.Error("ragged pattern accepted")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	db := testDB(t)
+	path := filepath.Join(t.TempDir(), "games.idx")
+	if err := db.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Games) != len(games) || loaded.Games[1].Black != "carol" {
+		t.Errorf("games: %+v", loaded.Games)
+	}
+	checkResult(t, mustSearch(t, loaded, play(t, 9, "cc", "gg", "cg", "gc")), 3, "Bee++ Bdc-")
+}
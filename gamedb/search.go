@@ -0,0 +1,256 @@
+package gamedb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+// Match is a game that reached a position
+type Match struct {
+	Game *Game
+	// Moves is the number of moves that had been played when the
+	// game first reached the position
+	Moves int
+}
+
+// Continuation summarizes the games that continued with a move
+type Continuation struct {
+	// Move is the point played, in the orientation of the query. A
+	// pass is -1,-1.
+	Move  sgf.Point
+	Color game.Color
+	Count int
+	// Wins and Losses count the games won and lost by the player
+	// who made the move
+	Wins, Losses int
+}
+
+// WinRate returns the fraction of decided games won by the player who
+// made the move
+func (c *Continuation) WinRate() float64 {
+	if c.Wins+c.Losses == 0 {
+		return 0
+	}
+	return float64(c.Wins) / float64(c.Wins+c.Losses)
+}
+
+// Result is the answer to a query
+type Result struct {
+	Matches []Match
+	// Continuations lists the moves that followed, most popular
+	// first
+	Continuations []*Continuation
+}
+
+type resultBuilder struct {
+	res   Result
+	conts map[move]*Continuation
+}
+
+// add records a match. next and winner have already been mapped into
+// the orientation of the query.
+func (b *resultBuilder) add(g *Game, moves int, next move, winner int8) {
+	b.res.Matches = append(b.res.Matches, Match{g, moves})
+	if !next.ok {
+		return
+	}
+	if b.conts == nil {
+		b.conts = make(map[move]*Continuation)
+	}
+	c := b.conts[next]
+	if c == nil {
+		c = &Continuation{Move: sgf.Point{X: next.x, Y: next.y}, Color: next.color == white}
+		b.conts[next] = c
+		b.res.Continuations = append(b.res.Continuations, c)
+	}
+	c.Count++
+	switch winner {
+	case empty:
+	case next.color:
+		c.Wins++
+	default:
+		c.Losses++
+	}
+}
+
+func (b *resultBuilder) result() *Result {
+	cs := b.res.Continuations
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].Count > cs[j].Count })
+	return &b.res
+}
+
+func winner(g *Game) int8 {
+	c, ok := g.Winner()
+	if !ok {
+		return empty
+	}
+	return colorOf(c)
+}
+
+// Search returns the games that reached the current position of g,
+// with the same player to play, in any orientation and with the
+// colors either way around
+func (db *DB) Search(g *game.Game) (*Result, error) {
+	if g.Width != g.Height {
+		return nil, ErrNotSquare
+	}
+	n := g.Width
+	board, toPlay := snapshot(g), colorOf(g.ToPlay())
+	hash, t := canonical(n, board, toPlay, move{})
+	// Moves are stored in each game's orientation; map them to the
+	// canonical board and from there to the query's. If the query is
+	// symmetric, equivalent continuations are reported at the
+	// smallest of their points, as canonical does, so the result does
+	// not depend on which transform it picked.
+	inv := t.inverse()
+	sym := symmetries(n, board, toPlay)
+	var b resultBuilder
+	for _, o := range db.positions[hash] {
+		gm := db.Games[o.Game]
+		if gm.Size != n {
+			continue
+		}
+		next := inv.move(n, o.Transform.move(n, o.next()))
+		w := inv.color(o.Transform.color(winner(gm)))
+		if next.ok {
+			s := next.smallest(n, sym)
+			next, w = s.move(n, next), s.color(w)
+		}
+		b.add(gm, int(o.Moves), next, w)
+	}
+	return b.result(), nil
+}
+
+// Pattern is a local arrangement of stones, given one row per string:
+// X is a black stone, O a white stone, . an empty point and ? a point
+// whose contents do not matter
+type Pattern struct {
+	Rows []string
+	// Corner requires the pattern's first row and column to lie
+	// along the edges of the board
+	Corner bool
+}
+
+func (p *Pattern) parse() (w, h int, cells []int8, err error) {
+	h = len(p.Rows)
+	if h == 0 {
+		return 0, 0, nil, errors.New("gamedb: empty pattern")
+	}
+	w = len(p.Rows[0])
+	for _, row := range p.Rows {
+		if len(row) != w {
+			return 0, 0, nil, errors.New("gamedb: pattern rows differ in length")
+		}
+		for _, r := range row {
+			switch r {
+			case 'X':
+				cells = append(cells, black)
+			case 'O':
+				cells = append(cells, white)
+			case '.':
+				cells = append(cells, empty)
+			case '?':
+				cells = append(cells, -1)
+			default:
+				return 0, 0, nil, fmt.Errorf("gamedb: bad pattern character %q", r)
+			}
+		}
+	}
+	if w == 0 {
+		return 0, 0, nil, errors.New("gamedb: empty pattern")
+	}
+	return w, h, cells, nil
+}
+
+// SearchPattern returns the games that contain a pattern, in any
+// orientation and with the colors either way around. Each game is
+// reported the first time the pattern appears, and its continuation
+// is given relative to the pattern's first row and column. Unlike
+// Search, this replays the indexed games, skipping those that never
+// have enough stones on the board to contain the pattern.
+func (db *DB) SearchPattern(p *Pattern) (*Result, error) {
+	pw, ph, cells, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	need := countStones(cells)
+	var b resultBuilder
+	for _, gm := range db.Games {
+		if pw > gm.Size || ph > gm.Size || !enough(gm.MaxStones, need) {
+			continue
+		}
+		c, err := sgf.ParseSGF(strings.NewReader(gm.Record))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", gm.Path, err)
+		}
+		n := gm.Size
+		view := make([]int8, n*n)
+		err = replay(c.Trees[0], func(board []int8, _ int8, moves int, next move) bool {
+			have := countStones(board)
+			if !enough(have, need) {
+				return true
+			}
+			for t := transform(0); t < numTransform; t++ {
+				if !covers(t.stones(have), need) {
+					continue
+				}
+				for i, c := range board {
+					x, y := t.point(n, i%n, i/n)
+					view[y*n+x] = t.color(c)
+				}
+				ox, oy, ok := matchAt(view, n, cells, pw, ph, p.Corner)
+				if !ok {
+					continue
+				}
+				next = t.move(n, next)
+				if next.ok && next.x >= 0 {
+					next.x -= ox
+					next.y -= oy
+				}
+				b.add(gm, moves, next, t.color(winner(gm)))
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", gm.Path, err)
+		}
+	}
+	return b.result(), nil
+}
+
+// covers reports whether a board with have black and white stones has
+// as many of each as a pattern needs
+func covers(have, need [2]int) bool {
+	return have[0] >= need[0] && have[1] >= need[1]
+}
+
+// enough is like covers, but allows the colors either way around
+func enough(have, need [2]int) bool {
+	return covers(have, need) || covers(swapBit.stones(have), need)
+}
+
+// matchAt finds a pattern on a board, returning its offset
+func matchAt(board []int8, n int, cells []int8, pw, ph int, corner bool) (int, int, bool) {
+	maxX, maxY := n-pw, n-ph
+	if corner {
+		maxX, maxY = 0, 0
+	}
+	for oy := 0; oy <= maxY; oy++ {
+	offsets:
+		for ox := 0; ox <= maxX; ox++ {
+			for i, c := range cells {
+				if c >= 0 && board[(oy+i/pw)*n+ox+i%pw] != c {
+					continue offsets
+				}
+			}
+			return ox, oy, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,161 @@
+package gamedb
+
+import (
+	"math/rand"
+
+	"nelhage.com/minigo/game"
+)
+
+// maxSize is the largest board size SGF can represent
+const maxSize = 52
+
+// Stone colors in a board snapshot
+const (
+	empty int8 = iota
+	black
+	white
+)
+
+var (
+	// stoneKeys holds a random key for each color and point
+	stoneKeys [2][maxSize * maxSize]uint64
+	// sizeKeys keeps positions on different board sizes apart
+	sizeKeys [maxSize + 1]uint64
+	// whiteKey distinguishes positions with white to play
+	whiteKey uint64
+)
+
+func init() {
+	// A fixed seed keeps hashes stable across runs, so that saved
+	// indexes remain valid
+	r := rand.New(rand.NewSource(0x5a0b))
+	for c := range stoneKeys {
+		for i := range stoneKeys[c] {
+			stoneKeys[c][i] = r.Uint64()
+		}
+	}
+	for i := range sizeKeys {
+		sizeKeys[i] = r.Uint64()
+	}
+	whiteKey = r.Uint64()
+}
+
+// A transform is one of the symmetries of a square board, in the low
+// bits, optionally combined with swapping the colors of the stones
+type transform uint8
+
+const (
+	swapBit      transform = 8
+	numTransform           = 16
+)
+
+func (t transform) symmetry() game.Symmetry {
+	return game.Symmetry(t &^ swapBit)
+}
+
+// point maps (x, y) on a board of size n
+func (t transform) point(n, x, y int) (int, int) {
+	return t.symmetry().Point(n, n, x, y)
+}
+
+// move maps a move
+func (t transform) move(n int, m move) move {
+	m.x, m.y = t.point(n, m.x, m.y)
+	m.color = t.color(m.color)
+	return m
+}
+
+func (t transform) color(c int8) int8 {
+	if t&swapBit != 0 && c != empty {
+		return black + white - c
+	}
+	return c
+}
+
+// stones maps counts of black and white stones
+func (t transform) stones(n [2]int) [2]int {
+	if t&swapBit != 0 {
+		n[0], n[1] = n[1], n[0]
+	}
+	return n
+}
+
+// inverse returns the transform that undoes t
+func (t transform) inverse() transform {
+	return transform(t.symmetry().Inverse()) | t&swapBit
+}
+
+// hashes returns the Zobrist hash of a board and the player to play
+// under every transform
+func hashes(n int, board []int8, toPlay int8) [numTransform]uint64 {
+	var h [numTransform]uint64
+	for t := range h {
+		h[t] = sizeKeys[n]
+		if transform(t).color(toPlay) == white {
+			h[t] ^= whiteKey
+		}
+	}
+	for i, c := range board {
+		if c == empty {
+			continue
+		}
+		for s := transform(0); s < swapBit; s++ {
+			x, y := s.point(n, i%n, i/n)
+			idx := y*maxSize + x
+			h[s] ^= stoneKeys[c-1][idx]
+			h[s|swapBit] ^= stoneKeys[white-c][idx]
+		}
+	}
+	return h
+}
+
+// canonical returns the canonical hash of a position, the smallest hash
+// over all transforms, and a transform that produces it. When several
+// transforms do, because the position is symmetric, the one that maps
+// next to the smallest point is chosen, so that equivalent
+// continuations are counted together.
+func canonical(n int, board []int8, toPlay int8, next move) (uint64, transform) {
+	h := hashes(n, board, toPlay)
+	best := transform(0)
+	for t := transform(1); t < numTransform; t++ {
+		switch {
+		case h[t] < h[best]:
+			best = t
+		case h[t] == h[best] && next.ok && next.less(n, t, best):
+			best = t
+		}
+	}
+	return h[best], best
+}
+
+// symmetries returns the transforms that leave a position unchanged,
+// starting with the identity
+func symmetries(n int, board []int8, toPlay int8) []transform {
+	h := hashes(n, board, toPlay)
+	var sym []transform
+	for t := transform(0); t < numTransform; t++ {
+		if h[t] == h[0] {
+			sym = append(sym, t)
+		}
+	}
+	return sym
+}
+
+// smallest returns, of the transforms in sym, the one that maps m to
+// the smallest point, preferring the earliest on a tie
+func (m move) smallest(n int, sym []transform) transform {
+	best := sym[0]
+	for _, t := range sym[1:] {
+		if m.less(n, t, best) {
+			best = t
+		}
+	}
+	return best
+}
+
+// less compares m under two transforms
+func (m move) less(n int, a, b transform) bool {
+	ma, mb := a.move(n, m), b.move(n, m)
+	ka, kb := ma.y*n+ma.x, mb.y*n+mb.x
+	return ka < kb || ka == kb && ma.color < mb.color
+}
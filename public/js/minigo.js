@@ -8,7 +8,13 @@
        return '';
      }
    }
-   var size = 9;
+   var markGlyphs = {
+     triangle: "\u25b3",
+     square: "\u25a1",
+     circle: "\u25cb",
+     cross: "\u00d7",
+     selected: "\u25cf",
+   };
 
    var GoSquare = React.createClass({
        doMove: function(e) {
@@ -23,9 +29,12 @@
            } else {
              classes.push(c)
            }
+           if (this.props.dimmed) {
+             classes.push("dimmed");
+           }
            return (
              <div className="square" data-coords={JSON.stringify([this.props.x,this.props.y])}>
-               <div className={classes.join(" ")} onClick={this.doMove}></div>
+               <div className={classes.join(" ")} onClick={this.doMove}>{this.props.markup}</div>
                <div className="grid"></div>
              </div>
            );
@@ -35,6 +44,8 @@
    var GoBoard = React.createClass({
        getInitialState: function() {
          return {
+           width: 9,
+           height: 9,
            positions: {},
            to_move: 'W',
          };
@@ -55,50 +66,111 @@
        at: function(x, y) {
          return this.state.positions[x+","+y];
        },
+       markupAt: function(x, y) {
+         var notes = this.state.annotations || {};
+         var text = "";
+         (notes.marks || []).forEach(function(m) {
+           if (m.x == x && m.y == y) {
+             text = markGlyphs[m.type];
+           }
+         });
+         (notes.labels || []).forEach(function(l) {
+           if (l.x == x && l.y == y) {
+             text = l.text;
+           }
+         });
+         return text;
+       },
+       dimmedAt: function(x, y) {
+         return (this.state.dimmed || []).some(function(p) {
+           return p[0] == x && p[1] == y;
+         });
+       },
+       join: function(color) {
+         $.ajax({
+           method: 'POST',
+           url: "/join",
+           dataType: 'json',
+           data: JSON.stringify({color: color}),
+           success: function(data) {
+             sessionStorage.setItem("minigo-token", data.token);
+             this.componentDidMount();
+           }.bind(this),
+           error: function(xhr, status, err) {
+             var reply = xhr.responseJSON || {};
+             console.error("join", reply.code || status, reply.error || err.toString());
+           }.bind(this),
+         });
+       },
+       authHeaders: function() {
+         var token = sessionStorage.getItem("minigo-token");
+         return token ? {Authorization: "Bearer " + token} : {};
+       },
        submitMove: function(pos){
          $.ajax({
            method: 'POST',
            url: "/move",
            dataType: 'json',
+           headers: this.authHeaders(),
            cache: false,
            data: JSON.stringify({
              x: pos.x,
              y: pos.y,
              to_move: this.state.to_move,
+             move_number: this.state.move_number,
            }),
            success: function(data) {
              this.setState(data);
            }.bind(this),
            error: function(xhr, status, err) {
-             console.error("doMove", status, err.toString());
+             var reply = xhr.responseJSON || {};
+             console.error("doMove", reply.code || status, reply.error || err.toString());
            }.bind(this),
          })
        },
        render: function() {
          var rows = [];
-         for (var y = 0; y < this.props.size; y++) {
+         for (var y = 0; y < this.state.height; y++) {
            var row = [];
-           for (var x = 0; x < this.props.size; x++) {
+           for (var x = 0; x < this.state.width; x++) {
              row.push(
                  <GoSquare
                      key={x} x={x} y={y}
                      contents={this.at(x,y)}
+                     markup={this.markupAt(x,y)}
+                     dimmed={this.dimmedAt(x,y)}
                      onSubmitMove={this.submitMove}
                  />);
            }
            rows.push(<div className="row" key={y}>{row}</div>);
          }
          var classes = ["goboard", longColor(this.state.to_move)];
+         var notes = this.state.annotations || {};
+         var seats = [];
+         if (this.state.seats && !sessionStorage.getItem("minigo-token")) {
+           ["B", "W"].forEach(function(c) {
+             if (!this.state.seats[c]) {
+               seats.push(
+                   <button key={c} onClick={this.join.bind(this, c)}>
+                     Play {longColor(c)}
+                   </button>);
+             }
+           }.bind(this));
+         }
          return (
-           <div className={classes.join(" ")}>
-             {rows}
+           <div>
+             <div className="seats">{seats}</div>
+             <div className={classes.join(" ")}>
+               {rows}
+             </div>
+             <div className="comment">{notes.comment}</div>
            </div>
          );
        }
    });
 
    ReactDOM.render(
-     <GoBoard size={size} />,
+     <GoBoard />,
      document.getElementById('content')
    );
  })();
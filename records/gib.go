@@ -0,0 +1,149 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// gibResults maps the GRLT result codes of GAMEINFOMAIN to a winner
+// and the way the game was won. An empty way means the game was won
+// on points, by the margin in ZIPSU.
+var gibResults = map[string][2]string{
+	"0": {"B", ""},
+	"1": {"W", ""},
+	"3": {"B", "R"},
+	"4": {"W", "R"},
+	"7": {"B", "T"},
+	"8": {"W", "T"},
+}
+
+// ParseGIB converts a Tygem .gib game record. The header is a series
+// of `\[KEY=VALUE\]' lines between `\HS' and `\HE', and the game
+// between `\GS' and `\GE' consists of commands: `INI 0 1 <handicap>'
+// sets up handicap stones, `STO 0 <n> <color> <x> <y>' plays a stone
+// (color 1 is black, 2 is white) and `SKI 0 <n>' passes.
+func ParseGIB(in io.Reader, opts Options) (*sgf.Collection, error) {
+	ls, err := lines(in, opts)
+	if err != nil {
+		return nil, err
+	}
+	r := &record{size: 19}
+	header := make(map[string]string)
+	inGame := false
+	last := "W"
+	for i, l := range ls {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == `\GS`:
+			inGame = true
+			continue
+		case l == `\GE`:
+			inGame = false
+			continue
+		case strings.HasPrefix(l, `\[`) && strings.HasSuffix(l, `\]`):
+			kv := strings.SplitN(l[2:len(l)-2], "=", 2)
+			if len(kv) == 2 {
+				header[kv[0]] = kv[1]
+			}
+			continue
+		}
+		if !inGame {
+			continue
+		}
+		f := strings.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+		bad := &FormatError{"gib", i + 1, fmt.Sprintf("bad command: %q", l)}
+		switch f[0] {
+		case "INI":
+			if len(f) < 4 {
+				return nil, bad
+			}
+			if r.handicap, err = strconv.Atoi(f[3]); err != nil {
+				return nil, bad
+			}
+			if r.handicap >= 2 {
+				last = "B"
+			}
+		case "STO":
+			if len(f) < 6 {
+				return nil, bad
+			}
+			x, xerr := strconv.Atoi(f[4])
+			y, yerr := strconv.Atoi(f[5])
+			if xerr != nil || yerr != nil || (f[3] != "1" && f[3] != "2") {
+				return nil, bad
+			}
+			last = map[string]string{"1": "B", "2": "W"}[f[3]]
+			r.moves = append(r.moves, move{last, x, y})
+		case "SKI":
+			last = map[string]string{"B": "W", "W": "B"}[last]
+			r.moves = append(r.moves, move{last, -1, -1})
+		}
+	}
+
+	main := make(map[string]string)
+	for _, kv := range strings.Split(header["GAMEINFOMAIN"], ",") {
+		if i := strings.IndexByte(kv, ':'); i > 0 {
+			main[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	r.set("GN", header["GAMENAME"])
+	r.set("EV", header["GAMEEVENT"])
+	r.set("PC", header["GAMEPLACE"])
+	for _, p := range []struct{ color, key string }{{"B", "BLACK"}, {"W", "WHITE"}} {
+		name, rank := player(header["GAME"+p.key+"NAME"])
+		if name == "" {
+			name = header["GAME"+p.key+"NICK"]
+		}
+		r.set("P"+p.color, name)
+		r.set(p.color+"R", normalizeRank(rank))
+	}
+	r.set("DT", gibDate(header["GAMEDATE"]))
+	for _, k := range []string{main["GONGJE"], main["DUM"], header["GAMEGONGJE"]} {
+		if tenths, err := strconv.Atoi(strings.TrimSpace(k)); err == nil && tenths != 0 {
+			r.komi = formatKomi(tenths)
+			break
+		}
+	}
+	if res, ok := gibResults[main["GRLT"]]; ok {
+		how := res[1]
+		if how == "" {
+			tenths, err := strconv.Atoi(main["ZIPSU"])
+			if err != nil {
+				return nil, &FormatError{"gib", 0, fmt.Sprintf("bad ZIPSU: %q", main["ZIPSU"])}
+			}
+			how = formatKomi(tenths)
+		}
+		r.set("RE", result(res[0], how))
+	} else {
+		r.set("RE", freeformResult(header["GAMERESULT"]))
+	}
+	if len(r.moves) == 0 && len(header) == 0 {
+		return nil, &FormatError{"gib", 0, "no game found"}
+	}
+	return r.collection(), nil
+}
+
+// gibDate converts a date like `2015- 3-21-15-42-12' to SGF form
+func gibDate(s string) string {
+	f := strings.Split(s, "-")
+	if len(f) < 3 {
+		return ""
+	}
+	var nums [3]int
+	for i := range nums {
+		n, err := strconv.Atoi(strings.TrimSpace(f[i]))
+		if err != nil {
+			return ""
+		}
+		nums[i] = n
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", nums[0], nums[1], nums[2])
+}
@@ -0,0 +1,113 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// ParseNGF converts a WBaduk .ngf game record. NGF files have a fixed
+// header of twelve lines: the game name, board size, white player,
+// black player, server, handicap, an unused field, komi, date, an
+// unused field, result and move count. Moves follow one per line as
+// `PM<nn><color><x><y><x><y>', with coordinates as letters starting
+// from `B' for the first line.
+func ParseNGF(in io.Reader, opts Options) (*sgf.Collection, error) {
+	ls, err := lines(in, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(ls) < 12 {
+		return nil, &FormatError{"ngf", len(ls), "truncated header"}
+	}
+	r := &record{}
+	if r.size, err = strconv.Atoi(strings.TrimSpace(ls[1])); err != nil ||
+		r.size < 2 || r.size > 25 {
+		return nil, &FormatError{"ngf", 2, fmt.Sprintf("bad board size: %q", ls[1])}
+	}
+	if r.handicap, err = strconv.Atoi(strings.TrimSpace(ls[5])); err != nil {
+		return nil, &FormatError{"ngf", 6, fmt.Sprintf("bad handicap: %q", ls[5])}
+	}
+	if komi, err := strconv.ParseFloat(strings.TrimSpace(ls[7]), 64); err == nil {
+		// WBaduk records whole-point komi and adds a half point
+		if komi == float64(int(komi)) && komi != 0 {
+			komi += 0.5
+		}
+		r.komi = strconv.FormatFloat(komi, 'f', -1, 64)
+	}
+
+	r.set("GN", ls[0])
+	for _, p := range []struct {
+		color string
+		line  int
+	}{{"W", 2}, {"B", 3}} {
+		name, rank := player(ls[p.line])
+		r.set("P"+p.color, name)
+		r.set(p.color+"R", normalizeRank(rank))
+	}
+	r.set("PC", ls[4])
+	r.set("DT", ngfDate(ls[8]))
+	r.set("RE", freeformResult(ls[10]))
+
+	for i, l := range ls[12:] {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, "PM") {
+			continue
+		}
+		if len(l) < 7 || (l[4] != 'B' && l[4] != 'W') {
+			return nil, &FormatError{"ngf", i + 13, fmt.Sprintf("bad move: %q", l)}
+		}
+		r.moves = append(r.moves, move{
+			color: string(l[4]),
+			x:     int(l[5]) - 'B',
+			y:     int(l[6]) - 'B',
+		})
+	}
+	return r.collection(), nil
+}
+
+// ngfDate converts a date like `20030810 [18:00]' to SGF form
+func ngfDate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 8 {
+		return ""
+	}
+	if _, err := strconv.Atoi(s[:8]); err != nil {
+		return ""
+	}
+	return s[:4] + "-" + s[4:6] + "-" + s[6:8]
+}
+
+// freeformResult interprets an English description of a game result,
+// such as `White wins by resign' or `Black wins by 3.5 points'
+func freeformResult(s string) string {
+	lower := strings.ToLower(s)
+	var winner string
+	switch {
+	case strings.Contains(lower, "white"):
+		winner = "W"
+	case strings.Contains(lower, "black"):
+		winner = "B"
+	case strings.Contains(lower, "draw") || strings.Contains(lower, "jigo"):
+		return "0"
+	default:
+		return ""
+	}
+	switch {
+	case strings.Contains(lower, "resign"):
+		return result(winner, "R")
+	case strings.Contains(lower, "time"):
+		return result(winner, "T")
+	case strings.Contains(lower, "forfeit"):
+		return result(winner, "F")
+	}
+	for _, f := range strings.Fields(lower) {
+		if _, err := strconv.ParseFloat(f, 64); err == nil {
+			return result(winner, f)
+		}
+	}
+	return winner + "+"
+}
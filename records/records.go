@@ -0,0 +1,225 @@
+// Package records converts game records from other Go servers'
+// formats into SGF. It understands Tygem .gib files, WBaduk .ngf
+// files and Pandanet (IGS) .ugf/.ugi files.
+package records
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// Options controls how records are read
+type Options struct {
+	// Charset is the character set of the input. If it is empty,
+	// input is read as UTF-8. Decoders for other character sets
+	// are looked up with sgf.NewDecoder.
+	Charset string
+}
+
+// A Parser converts a game record to an SGF collection
+type Parser func(in io.Reader, opts Options) (*sgf.Collection, error)
+
+var parsers = map[string]Parser{
+	".gib": ParseGIB,
+	".ngf": ParseNGF,
+	".ugf": ParseUGF,
+	".ugi": ParseUGF,
+}
+
+// ForFile returns the parser for a file based on its extension, or
+// nil if the format is not recognized
+func ForFile(path string) Parser {
+	return parsers[strings.ToLower(filepath.Ext(path))]
+}
+
+// FormatError is returned for input that does not match the expected
+// format
+type FormatError struct {
+	Format string
+	Line   int
+	Msg    string
+}
+
+func (e *FormatError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Format, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Format, e.Line, e.Msg)
+}
+
+// lines decodes the input and splits it into lines, without
+// trailing whitespace
+func lines(in io.Reader, opts Options) ([]string, error) {
+	if opts.Charset != "" {
+		var err error
+		if in, err = sgf.NewDecoder(opts.Charset, in); err != nil {
+			return nil, err
+		}
+	}
+	var out []string
+	s := bufio.NewScanner(in)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		out = append(out, strings.TrimRight(s.Text(), " \t\r\n\x00"))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) > 0 {
+		out[0] = strings.TrimPrefix(out[0], "\xef\xbb\xbf")
+	}
+	return out, nil
+}
+
+type move struct {
+	color string
+	x, y  int
+}
+
+// record accumulates a game in a format-independent way
+type record struct {
+	size     int
+	handicap int
+	komi     string
+	info     []sgf.Property
+	setup    []sgf.Point
+	moves    []move
+}
+
+func (r *record) set(prop, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	r.info = append(r.info, sgf.Property{Prop: prop, Values: []sgf.PropValue{sgf.PropValue(value)}})
+}
+
+// player splits a player description of the form `Name (5d)' or
+// `Name 5d' into name and rank
+func player(s string) (name, rank string) {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '('); i > 0 && strings.HasSuffix(s, ")") {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1 : len(s)-1])
+	}
+	if i := strings.LastIndexAny(s, " \t"); i > 0 && isRank(s[i+1:]) {
+		return strings.TrimSpace(s[:i]), s[i+1:]
+	}
+	return s, ""
+}
+
+// isRank returns true for strings like 5d, 12K, 3p or 1D*
+func isRank(s string) bool {
+	s = strings.TrimSuffix(s, "*")
+	if len(s) < 2 {
+		return false
+	}
+	if _, err := strconv.Atoi(s[:len(s)-1]); err != nil {
+		return false
+	}
+	return strings.ContainsAny(s[len(s)-1:], "dkpDKP")
+}
+
+// normalizeRank converts ranks to the lowercase form used in SGF
+func normalizeRank(s string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "*"))
+}
+
+// result builds an SGF RE value. winner is "B" or "W"; how is a
+// margin, or "R", "T" or "F" for resignation, time or forfeit.
+func result(winner, how string) string {
+	if winner == "" {
+		return ""
+	}
+	return winner + "+" + how
+}
+
+// handicapPoints returns the traditional placement of n handicap
+// stones on a board of the given size
+func handicapPoints(size, n int) []sgf.Point {
+	if n < 2 || n > 9 || size < 7 {
+		return nil
+	}
+	edge := 3
+	if size < 13 {
+		edge = 2
+	}
+	far, mid := size-1-edge, size/2
+	pt := func(x, y int) sgf.Point { return sgf.Point{X: x, Y: y} }
+	corners := []sgf.Point{pt(far, edge), pt(edge, far), pt(far, far), pt(edge, edge)}
+	sides := []sgf.Point{pt(edge, mid), pt(far, mid), pt(mid, edge), pt(mid, far)}
+	center := pt(mid, mid)
+	switch n {
+	case 2, 3, 4:
+		return corners[:n]
+	case 5:
+		return append(corners[:4:4], center)
+	case 6:
+		return append(corners[:4:4], sides[:2]...)
+	case 7:
+		return append(append(corners[:4:4], sides[:2]...), center)
+	case 8:
+		return append(corners[:4:4], sides...)
+	default:
+		return append(append(corners[:4:4], sides...), center)
+	}
+}
+
+// tree converts the record to an SGF game tree
+func (r *record) tree() *sgf.GameTree {
+	if r.size == 0 {
+		r.size = sgf.DefaultBoardSize
+	}
+	root := sgf.Node{Props: []sgf.Property{
+		{Prop: "FF", Values: []sgf.PropValue{"4"}},
+		{Prop: "GM", Values: []sgf.PropValue{"1"}},
+		{Prop: "SZ", Values: []sgf.PropValue{sgf.SizeValue(r.size, r.size)}},
+	}}
+	root.Props = append(root.Props, r.info...)
+	if r.komi != "" {
+		root.Props = append(root.Props, sgf.Property{Prop: "KM", Values: []sgf.PropValue{sgf.PropValue(r.komi)}})
+	}
+	if r.handicap >= 2 {
+		root.Props = append(root.Props, sgf.Property{
+			Prop:   "HA",
+			Values: []sgf.PropValue{sgf.PropValue(strconv.Itoa(r.handicap))},
+		})
+		if r.setup == nil {
+			r.setup = handicapPoints(r.size, r.handicap)
+		}
+	}
+	if r.setup != nil {
+		p := sgf.Property{Prop: "AB"}
+		for _, pt := range r.setup {
+			p.Values = append(p.Values, sgf.PointValue(pt.X, pt.Y))
+		}
+		root.Props = append(root.Props, p)
+	}
+	t := &sgf.GameTree{}
+	t.Principal.Nodes = append(t.Principal.Nodes, root)
+	for _, m := range r.moves {
+		v := sgf.PointValue(-1, -1)
+		if m.x >= 0 && m.x < r.size && m.y >= 0 && m.y < r.size {
+			v = sgf.PointValue(m.x, m.y)
+		}
+		t.Principal.Nodes = append(t.Principal.Nodes, sgf.Node{
+			Props: []sgf.Property{{Prop: m.color, Values: []sgf.PropValue{v}}},
+		})
+	}
+	return t
+}
+
+func (r *record) collection() *sgf.Collection {
+	return &sgf.Collection{Trees: []*sgf.GameTree{r.tree()}}
+}
+
+// formatKomi formats a komi in tenths of a point, as several formats
+// store it
+func formatKomi(tenths int) string {
+	return strconv.FormatFloat(float64(tenths)/10, 'f', -1, 64)
+}
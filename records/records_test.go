@@ -0,0 +1,86 @@
+package records
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+func TestFormats(t *testing.T) {
+	cases := []struct {
+		file string
+		want string
+	}{
+		{"testdata/game.gib",
+			"(;FF[4]GM[1]SZ[19]GN[Rated game]PB[Lee Sedol]BR[9d]PW[Gu Li]WR[9d]DT[2010-03-07]RE[W+3.5]KM[6.5]" +
+				";B[pd];W[dp];B[qp];W[dc];B[];W[cc])"},
+		{"testdata/game.ngf",
+			"(;FF[4]GM[1]SZ[9]GN[Friendly match]PW[shusaku]WR[3d]PB[genan]BR[8d]PC[www.wbaduk.com]DT[2003-08-10]RE[W+R]KM[0]" +
+				"HA[2]AB[gc][cg];W[dd];B[ff];W[bf])"},
+		{"testdata/game.ugf",
+			"(;FF[4]GM[1]SZ[19]GN[IGS Rated,]PC[Pandanet]RU[JAPANESE]PB[alice]BR[2k]PW[bob]WR[3d]DT[2003-01-13]RE[B+4.5]" +
+				"KM[0.5]HA[3]AB[dd][pd][pp];W[qq];B[dp];W[])"},
+	}
+	for _, tc := range cases {
+		f, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ForFile(tc.file)(f, Options{})
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		var buf bytes.Buffer
+		sgf.WriteSGF(&buf, c)
+		if got := strings.TrimSpace(buf.String()); got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.file, got, tc.want)
+		}
+		if diags := sgf.Validate(c); len(diags) != 0 {
+			t.Errorf("%s: %v", tc.file, diags)
+		}
+		if _, err := game.FromSGF(c.Trees[0]); err != nil {
+			t.Errorf("%s: replay: %v", tc.file, err)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	cases := []struct {
+		parse Parser
+		in    string
+	}{
+		{ParseGIB, "hello"},
+		{ParseGIB, "\\GS\nSTO 0 2 3 1 1\n\\GE\n"},
+		{ParseNGF, "short\n19\n"},
+		{ParseNGF, "x\n19\nw\nb\n\n0\n0\n0\n\n\n\n1\nPMAAX\n"},
+		{ParseUGF, "Title=x\n"},
+		{ParseUGF, "[Header]\n[Data]\nQC,X1\n"},
+	}
+	for _, tc := range cases {
+		if _, err := tc.parse(strings.NewReader(tc.in), Options{}); err == nil {
+			t.Errorf("%q: no error", tc.in)
+		}
+	}
+}
+
+func TestHandicapPoints(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		pts := handicapPoints(19, n)
+		if len(pts) != n {
+			t.Errorf("handicap %d: %v", n, pts)
+		}
+		seen := make(map[sgf.Point]bool)
+		for _, p := range pts {
+			if seen[p] {
+				t.Errorf("handicap %d: duplicate %v", n, p)
+			}
+			seen[p] = true
+		}
+	}
+}
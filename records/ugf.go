@@ -0,0 +1,123 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// ParseUGF converts a Pandanet .ugf or .ugi game record. These are
+// INI-style files: the [Header] section holds `Key=Value' game
+// information, and each line of the [Data] section is a move of the
+// form `<x><y>,<color><n>,<time>', with coordinates as letters from
+// `A' counted from the lower left. Moves numbered 0 are handicap
+// stones, and coordinates off the board are passes.
+func ParseUGF(in io.Reader, opts Options) (*sgf.Collection, error) {
+	ls, err := lines(in, opts)
+	if err != nil {
+		return nil, err
+	}
+	r := &record{size: 19}
+	header := make(map[string]string)
+	var data []int
+	section := ""
+	for i, l := range ls {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			section = strings.ToLower(l[1 : len(l)-1])
+			continue
+		}
+		switch section {
+		case "header":
+			if kv := strings.SplitN(l, "=", 2); len(kv) == 2 {
+				header[strings.ToLower(kv[0])] = kv[1]
+			}
+		case "data":
+			if l != "" {
+				data = append(data, i)
+			}
+		}
+	}
+	if section == "" {
+		return nil, &FormatError{"ugf", 0, "no [Header] section"}
+	}
+	if s, ok := header["size"]; ok {
+		if r.size, err = strconv.Atoi(strings.TrimSpace(s)); err != nil ||
+			r.size < 2 || r.size > 25 {
+			return nil, &FormatError{"ugf", 0, fmt.Sprintf("bad size: %q", s)}
+		}
+	}
+	hdcp := strings.Split(header["hdcp"], ",")
+	if h, err := strconv.Atoi(strings.TrimSpace(hdcp[0])); err == nil {
+		r.handicap = h
+	}
+	if len(hdcp) > 1 {
+		r.komi = strings.TrimSpace(hdcp[1])
+	}
+
+	r.set("GN", header["title"])
+	r.set("PC", header["place"])
+	r.set("RU", header["rule"])
+	for _, p := range []string{"B", "W"} {
+		f := strings.Split(header["player"+strings.ToLower(p)], ",")
+		r.set("P"+p, f[0])
+		if len(f) > 1 {
+			r.set(p+"R", normalizeRank(f[1]))
+		}
+	}
+	if d := strings.Split(header["date"], ","); d[0] != "" {
+		r.set("DT", strings.Replace(strings.TrimSpace(d[0]), "/", "-", -1))
+	}
+	r.set("RE", ugfResult(header["winner"]))
+
+	for _, i := range data {
+		f := strings.Split(ls[i], ",")
+		if len(f) < 2 || len(f[0]) != 2 || len(f[1]) < 2 {
+			return nil, &FormatError{"ugf", i + 1, fmt.Sprintf("bad move: %q", ls[i])}
+		}
+		color := f[1][:1]
+		n, err := strconv.Atoi(f[1][1:])
+		if (color != "B" && color != "W") || err != nil {
+			return nil, &FormatError{"ugf", i + 1, fmt.Sprintf("bad move: %q", ls[i])}
+		}
+		x := int(f[0][0]) - 'A'
+		y := r.size - 1 - (int(f[0][1]) - 'A')
+		if n == 0 {
+			r.setup = append(r.setup, sgf.Point{X: x, Y: y})
+			continue
+		}
+		r.moves = append(r.moves, move{color, x, y})
+	}
+	return r.collection(), nil
+}
+
+// ugfResult converts a Winner value such as `B,4.5' or `W,-1' (by
+// resignation)
+func ugfResult(s string) string {
+	f := strings.Split(s, ",")
+	winner := strings.ToUpper(strings.TrimSpace(f[0]))
+	switch winner {
+	case "B", "W":
+	case "D":
+		return "0"
+	default:
+		return ""
+	}
+	if len(f) < 2 {
+		return winner + "+"
+	}
+	how := strings.TrimSpace(f[1])
+	switch strings.ToUpper(how) {
+	case "C", "R", "-1":
+		return result(winner, "R")
+	case "T":
+		return result(winner, "T")
+	}
+	if m, err := strconv.ParseFloat(how, 64); err == nil && m >= 0 {
+		return result(winner, how)
+	}
+	return winner + "+"
+}
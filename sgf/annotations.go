@@ -0,0 +1,370 @@
+package sgf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Point is a zero-based column and row on the board
+type Point struct {
+	X, Y int
+}
+
+// MoveQuality is a judgement of a move, from the BM, TE, DO and IT
+// properties
+type MoveQuality int
+
+const (
+	// NoMoveQuality means the move is not annotated
+	NoMoveQuality MoveQuality = iota
+	// BadMove is BM
+	BadMove
+	// Tesuji is TE
+	Tesuji
+	// Doubtful is DO
+	Doubtful
+	// Interesting is IT
+	Interesting
+)
+
+var moveQualityProps = map[MoveQuality]string{
+	BadMove:     "BM",
+	Tesuji:      "TE",
+	Doubtful:    "DO",
+	Interesting: "IT",
+}
+
+var moveQualityNames = map[MoveQuality]string{
+	BadMove:     "bad",
+	Tesuji:      "tesuji",
+	Doubtful:    "doubtful",
+	Interesting: "interesting",
+}
+
+func (q MoveQuality) String() string {
+	return moveQualityNames[q]
+}
+
+// Judgement is an evaluation of a position, from the GB, GW, DM and
+// UC properties
+type Judgement int
+
+const (
+	// NoJudgement means the position is not annotated
+	NoJudgement Judgement = iota
+	// GoodForBlack is GB
+	GoodForBlack
+	// GoodForWhite is GW
+	GoodForWhite
+	// Even is DM
+	Even
+	// Unclear is UC
+	Unclear
+)
+
+var judgementProps = map[Judgement]string{
+	GoodForBlack: "GB",
+	GoodForWhite: "GW",
+	Even:         "DM",
+	Unclear:      "UC",
+}
+
+var judgementNames = map[Judgement]string{
+	GoodForBlack: "good_for_black",
+	GoodForWhite: "good_for_white",
+	Even:         "even",
+	Unclear:      "unclear",
+}
+
+func (j Judgement) String() string {
+	return judgementNames[j]
+}
+
+// MarkType is the shape of a point markup
+type MarkType int
+
+const (
+	// Triangle is TR
+	Triangle MarkType = iota
+	// Square is SQ
+	Square
+	// Circle is CR
+	Circle
+	// Cross is MA
+	Cross
+	// Selected is SL
+	Selected
+)
+
+var markProps = []string{"TR", "SQ", "CR", "MA", "SL"}
+
+var markNames = []string{"triangle", "square", "circle", "cross", "selected"}
+
+func (m MarkType) String() string {
+	return markNames[m]
+}
+
+// Mark is a shape drawn on a point
+type Mark struct {
+	Type MarkType
+	Point
+}
+
+// Label is text drawn on a point, from the LB property
+type Label struct {
+	Point
+	Text string
+}
+
+// Line connects two points, from the AR and LN properties
+type Line struct {
+	From, To Point
+}
+
+// Annotations holds the annotation and markup properties of a node
+type Annotations struct {
+	// Comment is C
+	Comment string
+	// Name is N
+	Name string
+	// Quality is the move annotation, and QualityEmphasis is 2 for
+	// a very bad move or strong tesuji
+	Quality         MoveQuality
+	QualityEmphasis int
+	// Judgement is the position annotation, and
+	// JudgementEmphasis is 2 for a strong judgement
+	Judgement         Judgement
+	JudgementEmphasis int
+	// Hotspot is HO
+	Hotspot bool
+
+	Marks  []Mark
+	Labels []Label
+	Arrows []Line
+	Lines  []Line
+	// Dimmed lists the points dimmed by DD. DD is inherited by
+	// later nodes, so HasDimmed distinguishes an explicit empty DD[]
+	// that clears dimming from the absence of DD.
+	Dimmed    []Point
+	HasDimmed bool
+}
+
+// Empty returns true if a contains no annotations
+func (a *Annotations) Empty() bool {
+	return a.Comment == "" && a.Name == "" &&
+		a.Quality == NoMoveQuality && a.Judgement == NoJudgement &&
+		!a.Hotspot && len(a.Marks) == 0 && len(a.Labels) == 0 &&
+		len(a.Arrows) == 0 && len(a.Lines) == 0 && !a.HasDimmed
+}
+
+func emphasis(v PropValue) (int, error) {
+	if v == "" {
+		return 1, nil
+	}
+	switch v {
+	case "1":
+		return 1, nil
+	case "2":
+		return 2, nil
+	}
+	return 0, fmt.Errorf("bad double: %q", string(v))
+}
+
+func points(p *Property) ([]Point, error) {
+	var out []Point
+	for _, v := range p.Values {
+		if v == "" {
+			continue
+		}
+		x0, y0, x1, y1, err := v.PointRange()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.Prop, err)
+		}
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				out = append(out, Point{x, y})
+			}
+		}
+	}
+	return out, nil
+}
+
+func line(p *Property, v PropValue) (Line, error) {
+	a, b, ok := v.Compose()
+	if !ok {
+		return Line{}, fmt.Errorf("%s: bad line: %q", p.Prop, string(v))
+	}
+	x0, y0, err := a.Point()
+	if err != nil || x0 < 0 {
+		return Line{}, fmt.Errorf("%s: bad line: %q", p.Prop, string(v))
+	}
+	x1, y1, err := b.Point()
+	if err != nil || x1 < 0 {
+		return Line{}, fmt.Errorf("%s: bad line: %q", p.Prop, string(v))
+	}
+	return Line{Point{x0, y0}, Point{x1, y1}}, nil
+}
+
+// simpleText converts an SGF SimpleText value, in which all
+// whitespace is equivalent to a space
+func simpleText(v PropValue) string {
+	return strings.Join(strings.Fields(string(v)), " ")
+}
+
+// Annotations extracts the annotation and markup properties of n
+func (n *Node) Annotations() (*Annotations, error) {
+	a := &Annotations{}
+	for i := range n.Props {
+		p := &n.Props[i]
+		var v PropValue
+		if len(p.Values) > 0 {
+			v = p.Values[0]
+		}
+		var err error
+		switch p.Prop {
+		case "C":
+			a.Comment = string(v)
+		case "N":
+			a.Name = simpleText(v)
+		case "BM", "TE", "DO", "IT":
+			for q, prop := range moveQualityProps {
+				if prop == p.Prop {
+					a.Quality = q
+				}
+			}
+			a.QualityEmphasis = 1
+			if p.Prop == "BM" || p.Prop == "TE" {
+				a.QualityEmphasis, err = emphasis(v)
+			}
+		case "GB", "GW", "DM", "UC":
+			for j, prop := range judgementProps {
+				if prop == p.Prop {
+					a.Judgement = j
+				}
+			}
+			a.JudgementEmphasis, err = emphasis(v)
+		case "HO":
+			a.Hotspot = true
+		case "TR", "SQ", "CR", "MA", "SL":
+			var pts []Point
+			pts, err = points(p)
+			for t, prop := range markProps {
+				if prop != p.Prop {
+					continue
+				}
+				for _, pt := range pts {
+					a.Marks = append(a.Marks, Mark{MarkType(t), pt})
+				}
+			}
+		case "LB":
+			for _, v := range p.Values {
+				pt, text, ok := v.Compose()
+				x, y, perr := pt.Point()
+				if !ok || perr != nil || x < 0 {
+					err = fmt.Errorf("LB: bad label: %q", string(v))
+					break
+				}
+				a.Labels = append(a.Labels, Label{Point{x, y}, simpleText(text)})
+			}
+		case "AR", "LN":
+			for _, v := range p.Values {
+				var l Line
+				if l, err = line(p, v); err != nil {
+					break
+				}
+				if p.Prop == "AR" {
+					a.Arrows = append(a.Arrows, l)
+				} else {
+					a.Lines = append(a.Lines, l)
+				}
+			}
+		case "DD":
+			a.HasDimmed = true
+			a.Dimmed, err = points(p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func pointValues(pts []Point) []PropValue {
+	var vs []PropValue
+	for _, pt := range pts {
+		vs = append(vs, PointValue(pt.X, pt.Y))
+	}
+	return vs
+}
+
+func doubleValue(emphasis int) PropValue {
+	if emphasis == 2 {
+		return "2"
+	}
+	return "1"
+}
+
+// Props returns the SGF properties that represent a
+func (a *Annotations) Props() []Property {
+	var out []Property
+	add := func(prop string, vs ...PropValue) {
+		out = append(out, Property{prop, vs})
+	}
+	if a.Name != "" {
+		add("N", PropValue(a.Name))
+	}
+	if a.Comment != "" {
+		add("C", PropValue(a.Comment))
+	}
+	switch a.Quality {
+	case BadMove, Tesuji:
+		add(moveQualityProps[a.Quality], doubleValue(a.QualityEmphasis))
+	case Doubtful, Interesting:
+		add(moveQualityProps[a.Quality], "")
+	}
+	if a.Judgement != NoJudgement {
+		add(judgementProps[a.Judgement], doubleValue(a.JudgementEmphasis))
+	}
+	if a.Hotspot {
+		add("HO", "1")
+	}
+	for t, prop := range markProps {
+		var pts []Point
+		for _, m := range a.Marks {
+			if m.Type == MarkType(t) {
+				pts = append(pts, m.Point)
+			}
+		}
+		if pts != nil {
+			add(prop, pointValues(pts)...)
+		}
+	}
+	if a.Labels != nil {
+		var vs []PropValue
+		for _, l := range a.Labels {
+			vs = append(vs, PointValue(l.X, l.Y)+":"+PropValue(l.Text))
+		}
+		add("LB", vs...)
+	}
+	for _, ls := range []struct {
+		prop  string
+		lines []Line
+	}{{"AR", a.Arrows}, {"LN", a.Lines}} {
+		if ls.lines == nil {
+			continue
+		}
+		var vs []PropValue
+		for _, l := range ls.lines {
+			vs = append(vs, PointValue(l.From.X, l.From.Y)+":"+PointValue(l.To.X, l.To.Y))
+		}
+		add(ls.prop, vs...)
+	}
+	if a.HasDimmed {
+		if len(a.Dimmed) == 0 {
+			add("DD", "")
+		} else {
+			add("DD", pointValues(a.Dimmed)...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package sgf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	tree := mustParse(t, `(;SZ[9];B[cc]C[a fine
+move]N[ joseki
+ ]TE[2]GB[]TR[aa][bb]MA[cd:dd]LB[ee:1][ff:x y]AR[aa:cc]LN[ab:ba]DD[])`)
+	n := &tree.Principal.Nodes[1]
+	a, err := n.Annotations()
+	if err != nil {
+		t.Fatal("Annotations:", err)
+	}
+	want := &Annotations{
+		Comment:           "a fine\nmove",
+		Name:              "joseki",
+		Quality:           Tesuji,
+		QualityEmphasis:   2,
+		Judgement:         GoodForBlack,
+		JudgementEmphasis: 1,
+		Marks: []Mark{
+			{Triangle, Point{0, 0}}, {Triangle, Point{1, 1}},
+			{Cross, Point{2, 3}}, {Cross, Point{3, 3}},
+		},
+		Labels:    []Label{{Point{4, 4}, "1"}, {Point{5, 5}, "x y"}},
+		Arrows:    []Line{{Point{0, 0}, Point{2, 2}}},
+		Lines:     []Line{{Point{0, 1}, Point{1, 0}}},
+		HasDimmed: true,
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("got  %+v\nwant %+v", a, want)
+	}
+
+	round, err := (&Node{Props: a.Props()}).Annotations()
+	if err != nil {
+		t.Fatal("Annotations:", err)
+	}
+	if !reflect.DeepEqual(round, want) {
+		t.Errorf("round trip: got %+v\nwant %+v", round, want)
+	}
+
+	bad := mustParse(t, "(;TE[3])")
+	if _, err := bad.Principal.Nodes[0].Annotations(); err == nil {
+		t.Errorf("TE[3] accepted")
+	}
+	if a, _ := tree.Principal.Nodes[0].Annotations(); !a.Empty() {
+		t.Errorf("root annotations: %+v", a)
+	}
+}
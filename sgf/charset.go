@@ -0,0 +1,431 @@
+package sgf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A Decoder wraps a reader producing text in some character set and
+// returns a reader producing the same text as UTF-8
+type Decoder func(io.Reader) io.Reader
+
+var (
+	charsetMu sync.RWMutex
+	charsets  = map[string]Decoder{
+		"UTF8":      nil,
+		"USASCII":   nil,
+		"ASCII":     nil,
+		"ISO88591":  decodeLatin1,
+		"LATIN1":    decodeLatin1,
+		"ISOLATIN1": decodeLatin1,
+		"GB2312":    decodeGB2312,
+		"EUCCN":     decodeGB2312,
+		"SHIFTJIS":  decodeShiftJIS,
+		"SJIS":      decodeShiftJIS,
+		"EUCKR":     decodeEUCKR,
+	}
+)
+
+// UTF8 is the CA value written for SGF output
+const UTF8 = "UTF-8"
+
+func charsetKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ':
+			return -1
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, name)
+}
+
+// RegisterCharset registers a decoder for the named character set,
+// as it would appear in a CA property, replacing any existing decoder
+// for that name. Names are matched case-insensitively, ignoring `-'
+// and `_'. UTF-8, US-ASCII, ISO-8859-1, GB2312, Shift_JIS and EUC-KR
+// are supported by default; other character sets may be supported by
+// registering a decoder for them.
+func RegisterCharset(name string, d Decoder) {
+	charsetMu.Lock()
+	defer charsetMu.Unlock()
+	if d == nil {
+		d = func(r io.Reader) io.Reader { return r }
+	}
+	charsets[charsetKey(name)] = d
+}
+
+// lookupCharset returns the decoder for a character set, and whether
+// the character set is known. A nil decoder indicates input that is
+// already UTF-8.
+func lookupCharset(name string) (Decoder, bool) {
+	charsetMu.RLock()
+	defer charsetMu.RUnlock()
+	d, ok := charsets[charsetKey(name)]
+	return d, ok
+}
+
+// NewDecoder returns a reader that decodes in from the named
+// character set to UTF-8, using the decoders registered with
+// RegisterCharset
+func NewDecoder(charset string, in io.Reader) (io.Reader, error) {
+	d, ok := lookupCharset(charset)
+	if !ok {
+		return nil, fmt.Errorf("unknown character set %q", charset)
+	}
+	if d == nil {
+		return in, nil
+	}
+	return d(in), nil
+}
+
+func isUTF8(name string) bool {
+	return charsetKey(name) == "UTF8"
+}
+
+type latin1Reader struct {
+	r   io.Reader
+	in  []byte
+	out []byte
+}
+
+func decodeLatin1(r io.Reader) io.Reader {
+	return &latin1Reader{r: r, in: make([]byte, 4096)}
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.out) == 0 {
+		n, err := l.r.Read(l.in)
+		for _, b := range l.in[:n] {
+			l.out = append(l.out, string(rune(b))...)
+		}
+		if len(l.out) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, l.out)
+	l.out = l.out[n:]
+	return n, nil
+}
+
+//go:generate python3 gen_charsets.py
+
+// dbcsTable is a 94x94 double-byte character set, built from its rows
+// in charset_tables.go the first time it is used
+type dbcsTable struct {
+	rows  *[94]string
+	once  sync.Once
+	runes []rune
+}
+
+var (
+	gb2312  = &dbcsTable{rows: &gb2312Rows}
+	jis0208 = &dbcsTable{rows: &jis0208Rows}
+	ksx1001 = &dbcsTable{rows: &ksx1001Rows}
+)
+
+// at returns the character at a zero-based row and column, or
+// utf8.RuneError if there is none
+func (t *dbcsTable) at(row, col int) rune {
+	t.once.Do(func() {
+		for _, r := range t.rows {
+			t.runes = append(t.runes, []rune(r)...)
+		}
+	})
+	if row < 0 || row >= 94 || col < 0 || col >= 94 {
+		return utf8.RuneError
+	}
+	return t.runes[row*94+col]
+}
+
+// mbcsReader decodes a multibyte character set, using next to decode
+// each character from its first byte and, if it needs them, the
+// bytes that follow
+type mbcsReader struct {
+	r    *bufio.Reader
+	next func(b byte, r *bufio.Reader) rune
+	out  []byte
+	err  error
+}
+
+func (m *mbcsReader) Read(p []byte) (int, error) {
+	for len(m.out) < len(p) && m.err == nil {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			m.err = err
+			break
+		}
+		m.out = utf8.AppendRune(m.out, m.next(b, m.r))
+	}
+	if len(m.out) == 0 {
+		return 0, m.err
+	}
+	n := copy(p, m.out)
+	m.out = m.out[n:]
+	return n, nil
+}
+
+// trailByte reads the next byte of a multibyte character if it is in
+// [lo, hi], leaving it to be read again otherwise
+func trailByte(r *bufio.Reader, lo, hi byte) (byte, bool) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	if b < lo || b > hi {
+		r.UnreadByte()
+		return 0, false
+	}
+	return b, true
+}
+
+// eucNext returns a function decoding an EUC encoding of t, in which
+// ASCII stands for itself and each other character is two bytes in
+// [0xA1, 0xFE]
+func eucNext(t *dbcsTable) func(byte, *bufio.Reader) rune {
+	return func(b byte, r *bufio.Reader) rune {
+		if b < utf8.RuneSelf {
+			return rune(b)
+		}
+		if b < 0xa1 || b > 0xfe {
+			return utf8.RuneError
+		}
+		b2, ok := trailByte(r, 0xa1, 0xfe)
+		if !ok {
+			return utf8.RuneError
+		}
+		return t.at(int(b-0xa1), int(b2-0xa1))
+	}
+}
+
+func decodeGB2312(r io.Reader) io.Reader {
+	return &mbcsReader{r: bufio.NewReader(r), next: eucNext(gb2312)}
+}
+
+func decodeEUCKR(r io.Reader) io.Reader {
+	return &mbcsReader{r: bufio.NewReader(r), next: eucNext(ksx1001)}
+}
+
+// shiftJISNext decodes a character in Shift_JIS. Since its trail bytes
+// include `\' and `]', decoding must happen before SGF is lexed.
+func shiftJISNext(b byte, r *bufio.Reader) rune {
+	switch {
+	case b < utf8.RuneSelf:
+		return rune(b)
+	case b >= 0xa1 && b <= 0xdf:
+		// Halfwidth katakana
+		return 0xff61 + rune(b-0xa1)
+	case b >= 0x81 && b <= 0x9f, b >= 0xe0 && b <= 0xfc:
+	default:
+		return utf8.RuneError
+	}
+	b2, ok := trailByte(r, 0x40, 0xfc)
+	if !ok || b2 == 0x7f {
+		return utf8.RuneError
+	}
+	// Each lead byte covers two rows of JIS X 0208
+	row := int(b-0x81) * 2
+	if b >= 0xe0 {
+		row = int(b-0xc1) * 2
+	}
+	var col int
+	switch {
+	case b2 >= 0x9f:
+		row, col = row+1, int(b2-0x9f)
+	case b2 > 0x7f:
+		col = int(b2 - 0x41)
+	default:
+		col = int(b2 - 0x40)
+	}
+	return jis0208.at(row, col)
+}
+
+func decodeShiftJIS(r io.Reader) io.Reader {
+	return &mbcsReader{r: bufio.NewReader(r), next: shiftJISNext}
+}
+
+// sniffLimit is the maximum amount of input examined when looking for
+// a CA property
+const sniffLimit = 64 << 10
+
+// rawByte returns the rune the lexer uses to carry byte b of
+// undecoded text through to a property value unchanged.
+func rawByte(b byte) rune {
+	return -1 - rune(b)
+}
+
+// isRawByte reports whether r was produced by rawByte, and if so
+// returns the byte.
+func isRawByte(r rune) (byte, bool) {
+	if r < 0 && r >= -256 {
+		return byte(-1 - r), true
+	}
+	return 0, false
+}
+
+// rawRuneReader reads UTF-8 like a bufio.Reader, but returns bytes
+// that are not valid UTF-8 as rawByte runes rather than as
+// utf8.RuneError, so that text in a character set with no registered
+// decoder survives parsing byte for byte.
+type rawRuneReader struct {
+	*bufio.Reader
+}
+
+func (r rawRuneReader) ReadRune() (rune, int, error) {
+	c, size, err := r.Reader.ReadRune()
+	if err == nil && c == utf8.RuneError && size == 1 {
+		r.Reader.UnreadRune()
+		b, _ := r.Reader.ReadByte()
+		return rawByte(b), 1, nil
+	}
+	return c, size, err
+}
+
+// sniffCharset looks for a CA property in the root node of the first
+// game tree in the input, without consuming any input. It returns the
+// property value and its position, or "" if there is none.
+func sniffCharset(r *bufio.Reader) (string, Pos) {
+	buf, _ := r.Peek(sniffLimit)
+	i := bytes.IndexByte(buf, '(')
+	if i < 0 {
+		return "", Pos{}
+	}
+	i++
+	for i < len(buf) && isSpace(rune(buf[i])) {
+		i++
+	}
+	if i >= len(buf) || buf[i] != ';' {
+		return "", Pos{}
+	}
+	i++
+	var ident []byte
+	for i < len(buf) {
+		c := buf[i]
+		switch {
+		case c == ';' || c == '(' || c == ')':
+			return "", Pos{}
+		case c == '[':
+			start := i + 1
+			for i++; i < len(buf) && buf[i] != ']'; i++ {
+				if buf[i] == '\\' {
+					i++
+				}
+			}
+			if string(ident) == "CA" && i < len(buf) {
+				return strings.TrimSpace(string(buf[start:i])), bytePos(buf, start)
+			}
+		case isUpper(rune(c)):
+			if i == 0 || !isUpper(rune(buf[i-1])) {
+				ident = ident[:0]
+			}
+			ident = append(ident, c)
+		}
+		i++
+	}
+	return "", Pos{}
+}
+
+// bytePos computes the position of byte offset `off` in buf
+func bytePos(buf []byte, off int) Pos {
+	p := Pos{Offset: off, Line: 1, Column: 1}
+	for _, r := range string(buf[:off]) {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
+// decodeInput determines the character set of the input and returns
+// a reader that produces it as UTF-8. The whole input is decoded
+// according to the first game tree's CA property; the lexer decodes a
+// stream, so later trees cannot switch character sets.
+func decodeInput(in io.Reader, opts Options, l *lexer) io.Reader {
+	br := bufio.NewReaderSize(in, sniffLimit)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	name, pos := sniffCharset(br)
+	if name == "" {
+		name = opts.Charset
+		pos = Pos{Line: 1, Column: 1}
+	}
+	if name == "" {
+		return br
+	}
+	d, ok := lookupCharset(name)
+	if !ok {
+		l.warnAt(pos, fmt.Sprintf("Unknown character set %q, leaving text undecoded", name))
+		l.undecoded = true
+		return br
+	}
+	if d == nil {
+		return br
+	}
+	return d(br)
+}
+
+// treeIsASCII reports whether every property value in a tree is
+// plain ASCII
+func treeIsASCII(t *GameTree) bool {
+	for _, n := range t.Principal.Nodes {
+		for _, p := range n.Props {
+			for _, v := range p.Values {
+				for i := 0; i < len(v); i++ {
+					if v[i] >= utf8.RuneSelf {
+						return false
+					}
+				}
+			}
+		}
+	}
+	for _, c := range t.Children {
+		if !treeIsASCII(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// outputRoot returns the root node of t as it should be written:
+// since text is decoded to UTF-8 as it is parsed, any CA property is
+// replaced with CA[UTF-8], and one is added if the tree contains
+// non-ASCII text. A CA naming a character set with no registered
+// decoder is kept, since text in it was never decoded.
+func outputRoot(t *GameTree) *Node {
+	root := &t.Principal.Nodes[0]
+	if v, ok := root.Value("CA"); ok {
+		if _, known := lookupCharset(string(v)); !known || isUTF8(string(v)) {
+			return root
+		}
+	}
+	out := &Node{}
+	found := false
+	for _, p := range root.Props {
+		if p.Prop == "CA" {
+			if found {
+				continue
+			}
+			found = true
+			p = Property{"CA", []PropValue{UTF8}}
+		}
+		out.Props = append(out.Props, p)
+	}
+	if !found {
+		if treeIsASCII(t) {
+			return root
+		}
+		out.Props = append(out.Props, Property{"CA", []PropValue{UTF8}})
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package sgf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// obsoleteProps maps FF[1]-FF[3] properties that were removed in
+// FF[4] to their FF[4] replacements. An empty replacement means the
+// property is dropped.
+var obsoleteProps = map[string]string{
+	"L":  "LB",
+	"M":  "MA",
+	"BS": "",
+	"WS": "",
+	"CH": "",
+	"EL": "",
+	"EX": "",
+	"ID": "",
+	"LT": "",
+	"OM": "",
+	"OP": "",
+	"OV": "",
+	"RG": "",
+	"SC": "",
+	"SE": "",
+	"SI": "",
+	"TC": "",
+}
+
+// convert records that a compatibility conversion was applied,
+// reporting each distinct conversion once
+func (l *lexer) convert(msg string) {
+	if l.converted == nil {
+		l.converted = make(map[string]bool)
+	}
+	if l.converted[msg] {
+		return
+	}
+	l.converted[msg] = true
+	l.warnAt(l.tokPos, msg)
+}
+
+// compatName converts an old-style property identifier to its FF[4]
+// equivalent. It returns false if the property should be discarded.
+func (l *lexer) compatName(raw string) (string, bool) {
+	name := strings.Map(func(r rune) rune {
+		if isUpper(r) {
+			return r
+		}
+		return -1
+	}, raw)
+	if name == "" {
+		l.recover(l.tokPos, fmt.Sprintf("Unexpected text `%s'", raw))
+		return "", false
+	}
+	if name != raw {
+		l.convert(fmt.Sprintf("Converted %s to %s", raw, name))
+	}
+	l.propFrom = name
+	to, ok := obsoleteProps[name]
+	if !ok {
+		return name, true
+	}
+	if to == "" {
+		l.convert(fmt.Sprintf("Dropped obsolete property %s", name))
+		return "", false
+	}
+	l.convert(fmt.Sprintf("Converted obsolete property %s to %s", name, to))
+	return to, true
+}
+
+// compatValue converts the value of an old-style property
+func (l *lexer) compatValue(v PropValue) PropValue {
+	switch l.propFrom {
+	case "L":
+		// FF[3] L labels points with successive letters
+		v = PropValue(fmt.Sprintf("%s:%c", v, 'A'+l.valIdx%26))
+	case "FF":
+		if n, err := v.Number(); err == nil && n < 4 {
+			l.convert(fmt.Sprintf("Converted FF[%d] to FF[4]", n))
+			v = "4"
+		}
+	}
+	l.valIdx++
+	return v
+}
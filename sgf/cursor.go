@@ -0,0 +1,271 @@
+package sgf
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoVariation is returned when navigating to a variation
+	// that does not exist
+	ErrNoVariation = errors.New("no such variation")
+
+	// ErrRootNode is returned by edits that would delete the root
+	// node of a game tree
+	ErrRootNode = errors.New("cannot delete the root node")
+)
+
+type cursorFrame struct {
+	tree  *GameTree
+	child int
+}
+
+// Cursor points at a node within a GameTree, and supports navigating
+// and editing the tree. Edits keep the tree in normal form: a
+// GameTree never has exactly one child, since a single continuation
+// belongs in the principal sequence.
+//
+// Pointers returned by Node are invalidated by edits.
+type Cursor struct {
+	root *GameTree
+	// stack holds the ancestors of tree, and the index of the
+	// child taken at each
+	stack []cursorFrame
+	tree  *GameTree
+	node  int
+}
+
+// NewCursor returns a cursor pointing at the root node of t
+func NewCursor(t *GameTree) *Cursor {
+	return &Cursor{root: t, tree: t}
+}
+
+// Node returns the node the cursor points at
+func (c *Cursor) Node() *Node {
+	return &c.tree.Principal.Nodes[c.node]
+}
+
+// Path returns the path to the current node
+func (c *Cursor) Path() Path {
+	p := Path{Node: c.node}
+	for _, f := range c.stack {
+		p.Variations = append(p.Variations, f.child)
+	}
+	return p
+}
+
+// AtRoot returns true if the cursor points at the root node
+func (c *Cursor) AtRoot() bool {
+	return len(c.stack) == 0 && c.node == 0
+}
+
+// Root moves the cursor to the root node
+func (c *Cursor) Root() {
+	c.stack = c.stack[:0]
+	c.tree = c.root
+	c.node = 0
+}
+
+// Variations returns the nodes that follow the current node. The
+// first is the main line; there is more than one only at a branch
+// point.
+func (c *Cursor) Variations() []*Node {
+	if c.node+1 < len(c.tree.Principal.Nodes) {
+		return []*Node{&c.tree.Principal.Nodes[c.node+1]}
+	}
+	var out []*Node
+	for _, ch := range c.tree.Children {
+		out = append(out, &ch.Principal.Nodes[0])
+	}
+	return out
+}
+
+// Next moves to the next node on the main line, returning false if
+// the current node is a leaf
+func (c *Cursor) Next() bool {
+	return c.SelectVariation(0) == nil
+}
+
+// SelectVariation moves to the i'th node returned by Variations
+func (c *Cursor) SelectVariation(i int) error {
+	if c.node+1 < len(c.tree.Principal.Nodes) {
+		if i != 0 {
+			return ErrNoVariation
+		}
+		c.node++
+		return nil
+	}
+	if i < 0 || i >= len(c.tree.Children) {
+		return ErrNoVariation
+	}
+	c.stack = append(c.stack, cursorFrame{c.tree, i})
+	c.tree = c.tree.Children[i]
+	c.node = 0
+	return nil
+}
+
+// Prev moves to the parent of the current node, returning false at
+// the root
+func (c *Cursor) Prev() bool {
+	if c.node > 0 {
+		c.node--
+		return true
+	}
+	if len(c.stack) == 0 {
+		return false
+	}
+	c.pop()
+	return true
+}
+
+// pop moves to the last node of the parent GameTree
+func (c *Cursor) pop() {
+	f := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	c.tree = f.tree
+	c.node = len(c.tree.Principal.Nodes) - 1
+}
+
+// Goto moves the cursor to the node identified by p. The cursor is
+// unchanged if p does not exist.
+func (c *Cursor) Goto(p Path) error {
+	stack := c.stack[:0:0]
+	t := c.root
+	for _, v := range p.Variations {
+		if v < 0 || v >= len(t.Children) {
+			return fmt.Errorf("%v: %v", p, ErrNoVariation)
+		}
+		stack = append(stack, cursorFrame{t, v})
+		t = t.Children[v]
+	}
+	if p.Node < 0 || p.Node >= len(t.Principal.Nodes) {
+		return fmt.Errorf("%v: no such node", p)
+	}
+	c.stack, c.tree, c.node = stack, t, p.Node
+	return nil
+}
+
+// split ensures the current node is the last in its sequence by
+// moving any later nodes, with the existing children, into a single
+// child GameTree
+func (c *Cursor) split() {
+	nodes := c.tree.Principal.Nodes
+	if c.node+1 == len(nodes) {
+		return
+	}
+	tail := &GameTree{
+		Principal: Sequence{Nodes: append([]Node(nil), nodes[c.node+1:]...)},
+		Children:  c.tree.Children,
+	}
+	c.tree.Principal.Nodes = nodes[:c.node+1]
+	c.tree.Children = []*GameTree{tail}
+}
+
+// normalize merges t's child into it if it has exactly one
+func normalize(t *GameTree) {
+	if len(t.Children) != 1 {
+		return
+	}
+	ch := t.Children[0]
+	t.Principal.Nodes = append(t.Principal.Nodes, ch.Principal.Nodes...)
+	t.Children = ch.Children
+}
+
+// InsertNode inserts n as the main-line continuation of the current
+// node, ahead of any existing continuations, and moves to it
+func (c *Cursor) InsertNode(n Node) {
+	nodes := c.tree.Principal.Nodes
+	nodes = append(nodes, Node{})
+	copy(nodes[c.node+2:], nodes[c.node+1:])
+	nodes[c.node+1] = n
+	c.tree.Principal.Nodes = nodes
+	c.node++
+}
+
+// AddVariation adds n as a new continuation of the current node,
+// after any existing ones, and moves to it. If the current node is a
+// leaf, n simply becomes its continuation.
+func (c *Cursor) AddVariation(n Node) {
+	if c.node+1 == len(c.tree.Principal.Nodes) && len(c.tree.Children) == 0 {
+		c.InsertNode(n)
+		return
+	}
+	c.split()
+	c.tree.Children = append(c.tree.Children, &GameTree{
+		Principal: Sequence{Nodes: []Node{n}},
+	})
+	c.SelectVariation(len(c.tree.Children) - 1)
+}
+
+// PromoteVariation makes the i'th continuation of the current node
+// its main line, preserving the order of the others
+func (c *Cursor) PromoteVariation(i int) error {
+	if i == 0 {
+		return nil
+	}
+	if c.node+1 < len(c.tree.Principal.Nodes) || i < 0 || i >= len(c.tree.Children) {
+		return ErrNoVariation
+	}
+	ch := c.tree.Children
+	promoted := ch[i]
+	copy(ch[1:i+1], ch[:i])
+	ch[0] = promoted
+	return nil
+}
+
+// PromoteToMainLine makes the line through the current node the main
+// line at every branch point above it
+func (c *Cursor) PromoteToMainLine() {
+	for i := range c.stack {
+		f := &c.stack[i]
+		ch := f.tree.Children
+		promoted := ch[f.child]
+		copy(ch[1:f.child+1], ch[:f.child])
+		ch[0] = promoted
+		f.child = 0
+	}
+}
+
+// DeleteSubtree deletes the current node and everything following it,
+// and moves to its parent
+func (c *Cursor) DeleteSubtree() error {
+	if c.AtRoot() {
+		return ErrRootNode
+	}
+	if c.node > 0 {
+		c.tree.Principal.Nodes = c.tree.Principal.Nodes[:c.node]
+		c.tree.Children = nil
+		c.node--
+		return nil
+	}
+	f := c.stack[len(c.stack)-1]
+	ch := f.tree.Children
+	f.tree.Children = append(ch[:f.child:f.child], ch[f.child+1:]...)
+	c.pop()
+	normalize(c.tree)
+	return nil
+}
+
+// SetProperty sets a property of the current node, replacing any
+// existing values
+func (c *Cursor) SetProperty(prop string, values ...PropValue) {
+	n := c.Node()
+	if p := n.Get(prop); p != nil {
+		p.Values = values
+		return
+	}
+	n.Props = append(n.Props, Property{prop, values})
+}
+
+// RemoveProperty removes a property from the current node, returning
+// false if it was not present
+func (c *Cursor) RemoveProperty(prop string) bool {
+	n := c.Node()
+	for i, p := range n.Props {
+		if p.Prop == prop {
+			n.Props = append(n.Props[:i], n.Props[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
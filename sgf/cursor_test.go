@@ -0,0 +1,113 @@
+package sgf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, in string) *GameTree {
+	c, err := ParseSGF(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parse %q: %v", in, err)
+	}
+	return c.Trees[0]
+}
+
+func sgfString(t *GameTree) string {
+	var buf bytes.Buffer
+	WriteSGF(&buf, &Collection{Trees: []*GameTree{t}})
+	return strings.Replace(strings.TrimSpace(buf.String()), "\n", "", -1)
+}
+
+func TestCursorNavigate(t *testing.T) {
+	c := NewCursor(mustParse(t, basic))
+	var moves []string
+	for c.Next() {
+		for _, p := range c.Node().Props {
+			if p.Prop == "B" || p.Prop == "W" {
+				moves = append(moves, p.Prop+string(p.Values[0]))
+			}
+		}
+	}
+	if got := strings.Join(moves, " "); got != "Baf Wah Bce Wag" {
+		t.Errorf("main line: %s", got)
+	}
+	if v, _ := c.Node().Value("C"); v != "only one eye this way" {
+		t.Errorf("C=%q", v)
+	}
+	if p := c.Path().String(); p != "0.0.1" {
+		t.Errorf("path=%s", p)
+	}
+
+	c.Root()
+	c.Next()
+	if n := len(c.Variations()); n != 3 {
+		t.Fatalf("%d variations", n)
+	}
+	if err := c.Goto(Path{Variations: []int{1, 0, 1}, Node: 2}); err != nil {
+		t.Fatal("goto:", err)
+	}
+	if v, _ := c.Node().Value("C"); !strings.HasPrefix(string(v), "RIGHT") {
+		t.Errorf("C=%q", v)
+	}
+	steps := 0
+	for c.Prev() {
+		steps++
+	}
+	if steps != 10 || !c.AtRoot() {
+		t.Errorf("%d steps back to root", steps)
+	}
+	if err := c.Goto(Path{Variations: []int{3}}); err == nil {
+		t.Errorf("goto bad path succeeded")
+	}
+	if err := c.SelectVariation(1); err != ErrNoVariation {
+		t.Errorf("SelectVariation(1) at root: %v", err)
+	}
+}
+
+func TestCursorEdit(t *testing.T) {
+	tree := mustParse(t, "(;SZ[9];B[aa];W[bb];B[cc])")
+	c := NewCursor(tree)
+	c.Next()
+	c.AddVariation(Node{Props: []Property{{"W", []PropValue{"dd"}}}})
+	if got := sgfString(tree); got != "(;SZ[9];B[aa](;W[bb];B[cc])(;W[dd]))" {
+		t.Errorf("add variation: %s", got)
+	}
+	if p := c.Path().String(); p != "1.0" {
+		t.Errorf("path=%s", p)
+	}
+	c.InsertNode(Node{Props: []Property{{"B", []PropValue{"ee"}}}})
+	c.SetProperty("C", "good")
+	c.SetProperty("C", "better")
+	c.PromoteToMainLine()
+	if got := sgfString(tree); got != "(;SZ[9];B[aa](;W[dd];B[ee]C[better])(;W[bb];B[cc]))" {
+		t.Errorf("promote: %s", got)
+	}
+	if !c.RemoveProperty("C") || c.RemoveProperty("C") {
+		t.Errorf("RemoveProperty")
+	}
+	c.Prev()
+	if err := c.DeleteSubtree(); err != nil {
+		t.Fatal("delete:", err)
+	}
+	if got := sgfString(tree); got != "(;SZ[9];B[aa];W[bb];B[cc])" {
+		t.Errorf("delete: %s", got)
+	}
+	if p := c.Path().String(); p != "1" {
+		t.Errorf("path=%s", p)
+	}
+	c.Next()
+	c.AddVariation(Node{Props: []Property{{"B", []PropValue{"ff"}}}})
+	c.Prev()
+	if err := c.PromoteVariation(1); err != nil {
+		t.Fatal("promote:", err)
+	}
+	if got := sgfString(tree); got != "(;SZ[9];B[aa];W[bb](;B[ff])(;B[cc]))" {
+		t.Errorf("promote variation: %s", got)
+	}
+	c.Root()
+	if err := c.DeleteSubtree(); err != ErrRootNode {
+		t.Errorf("deleted root: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+#!/usr/bin/env python3
+"""Generates charset_tables.go, the double-byte character set tables
+used to decode SGF files in GB2312, Shift_JIS and EUC-KR, from the
+codecs in Python's standard library."""
+
+import unicodedata
+
+
+def sjis(row, col):
+    """Returns the Shift_JIS encoding of a JIS X 0208 row and column"""
+    s1 = (row >> 1) + (0x81 if row < 62 else 0xC1)
+    if row & 1:
+        s2 = col + 0x9F
+    else:
+        s2 = col + 0x40
+        if s2 >= 0x7F:
+            s2 += 1
+    return bytes([s1, s2])
+
+
+def euc(row, col):
+    return bytes([row + 0xA1, col + 0xA1])
+
+
+def quote(s):
+    out = []
+    for c in s:
+        cat = unicodedata.category(c)
+        if c in '"\\\ufffd' or cat[0] in 'CMZ':
+            out.append('\\u%04x' % ord(c))
+        else:
+            out.append(c)
+    return '"' + ''.join(out) + '"'
+
+
+def rows(codec, encode):
+    out = []
+    for row in range(94):
+        chars = []
+        for col in range(94):
+            try:
+                c = encode(row, col).decode(codec)
+            except UnicodeDecodeError:
+                c = '\ufffd'
+            assert len(c) == 1 and ord(c) >= 0x80, (codec, row, col, c)
+            chars.append(c)
+        out.append(''.join(chars))
+    return out
+
+
+TABLES = [
+    ('gb2312Rows', 'GB 2312', 'gb2312', euc),
+    ('jis0208Rows', 'JIS X 0208, as used by Shift_JIS', 'shift_jis', sjis),
+    ('ksx1001Rows', 'KS X 1001, as used by EUC-KR', 'euc_kr', euc),
+]
+
+
+def main():
+    with open('charset_tables.go', 'w') as f:
+        f.write('// Code generated by gen_charsets.py; DO NOT EDIT.\n\n')
+        f.write('package sgf\n')
+        for name, desc, codec, encode in TABLES:
+            f.write('\n// %s holds %s, one string of 94 characters per\n' % (name, desc))
+            f.write('// row, with U+FFFD at unassigned points\n')
+            f.write('var %s = [94]string{\n' % name)
+            for r in rows(codec, encode):
+                f.write('\t%s,\n' % quote(r))
+            f.write('}\n')
+
+
+if __name__ == '__main__':
+    main()
@@ -0,0 +1,186 @@
+package sgf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The JSON encoding of a collection mirrors the SGF structure while
+// preserving property order and multi-valued properties:
+//
+//	{"trees": [
+//	  {"nodes": [
+//	     [["FF", "4"], ["SZ", "9"], ["AB", "cc", "gg"]],
+//	     [["B", "ee"], ["C", "a comment"]]
+//	   ],
+//	   "children": [ ...game trees... ]}
+//	]}
+//
+// A node is an array of properties, and a property is an array whose
+// first element is the property identifier and whose remaining
+// elements are its values, unescaped. "children" is omitted when a
+// game tree has no variations. As with WriteSGF, text is always UTF-8
+// and the CA property of each root node is rewritten to say so.
+
+type jsonCollection struct {
+	Trees []*GameTree `json:"trees"`
+}
+
+type jsonTree struct {
+	Nodes    []Node      `json:"nodes"`
+	Children []*GameTree `json:"children,omitempty"`
+}
+
+// variation is a GameTree below the top level, whose first node is
+// not a root node
+type variation GameTree
+
+type jsonVariation struct {
+	Nodes    []Node       `json:"nodes"`
+	Children []*variation `json:"children,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (c *Collection) MarshalJSON() ([]byte, error) {
+	trees := c.Trees
+	if trees == nil {
+		trees = []*GameTree{}
+	}
+	return json.Marshal(jsonCollection{trees})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *Collection) UnmarshalJSON(data []byte) error {
+	var jc jsonCollection
+	if err := json.Unmarshal(data, &jc); err != nil {
+		return err
+	}
+	for _, t := range jc.Trees {
+		if t == nil {
+			return errors.New("sgf: null game tree")
+		}
+	}
+	c.Trees = jc.Trees
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The root node of t is
+// written as it would be by WriteSGF.
+func (t *GameTree) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonVariation{jsonNodes(t, true), variations(t.Children)})
+}
+
+// MarshalJSON implements json.Marshaler
+func (v *variation) MarshalJSON() ([]byte, error) {
+	t := (*GameTree)(v)
+	return json.Marshal(jsonVariation{jsonNodes(t, false), variations(t.Children)})
+}
+
+func variations(ts []*GameTree) []*variation {
+	out := make([]*variation, len(ts))
+	for i, t := range ts {
+		out[i] = (*variation)(t)
+	}
+	return out
+}
+
+// jsonNodes returns the nodes of t's principal variation, replacing
+// the root node if t is a top-level tree
+func jsonNodes(t *GameTree, top bool) []Node {
+	nodes := t.Principal.Nodes
+	if nodes == nil {
+		return []Node{}
+	}
+	if !top {
+		return nodes
+	}
+	root := outputRoot(t)
+	if root == &nodes[0] {
+		return nodes
+	}
+	return append([]Node{*root}, nodes[1:]...)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *GameTree) UnmarshalJSON(data []byte) error {
+	var jt jsonTree
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+	if len(jt.Nodes) == 0 {
+		return errors.New("sgf: game tree has no nodes")
+	}
+	for _, c := range jt.Children {
+		if c == nil {
+			return errors.New("sgf: null game tree")
+		}
+	}
+	t.Principal.Nodes = jt.Nodes
+	t.Children = jt.Children
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (n *Node) MarshalJSON() ([]byte, error) {
+	props := n.Props
+	if props == nil {
+		props = []Property{}
+	}
+	return json.Marshal(props)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var props []Property
+	if err := json.Unmarshal(data, &props); err != nil {
+		return err
+	}
+	n.Props = props
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p *Property) MarshalJSON() ([]byte, error) {
+	out := make([]string, 0, len(p.Values)+1)
+	out = append(out, p.Prop)
+	for _, v := range p.Values {
+		out = append(out, string(v))
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *Property) UnmarshalJSON(data []byte) error {
+	var vals []string
+	if err := json.Unmarshal(data, &vals); err != nil {
+		return err
+	}
+	if len(vals) < 2 {
+		return fmt.Errorf("sgf: property %s has no values", data)
+	}
+	if !validIdent(vals[0]) {
+		return fmt.Errorf("sgf: bad property identifier: %q", vals[0])
+	}
+	p.Prop = vals[0]
+	p.Values = make([]PropValue, len(vals)-1)
+	for i, v := range vals[1:] {
+		p.Values[i] = PropValue(v)
+	}
+	return nil
+}
+
+// WriteJSON serializes a collection in its JSON form
+func WriteJSON(out io.Writer, c *Collection) error {
+	return json.NewEncoder(out).Encode(c)
+}
+
+// ParseJSON reads a collection in the JSON form written by WriteJSON
+func ParseJSON(in io.Reader) (*Collection, error) {
+	c := &Collection{}
+	if err := json.NewDecoder(in).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
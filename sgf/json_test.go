@@ -0,0 +1,49 @@
+package sgf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tree := mustParse(t, basic)
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, &Collection{Trees: []*GameTree{tree}}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sgfString(c.Trees[0]), sgfString(tree); got != want {
+		t.Errorf("round trip:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	tree := mustParse(t, `(;FF[4]C[caf\]é]AB[aa][bb](;B[cc])(;W[dd];C[ça]))`)
+	var buf bytes.Buffer
+	WriteJSON(&buf, &Collection{Trees: []*GameTree{tree}})
+	got := strings.Join(strings.Fields(buf.String()), "")
+	want := `{"trees":[{"nodes":[[["FF","4"],["C","caf]é"],["AB","aa","bb"],["CA","UTF-8"]]],` +
+		`"children":[{"nodes":[[["B","cc"]]]},{"nodes":[[["W","dd"]],[["C","ça"]]]}]}]}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"trees":[{"nodes":[]}]}`,
+		`{"trees":[null]}`,
+		`{"trees":[{"nodes":[[["B"]]]}]}`,
+		`{"trees":[{"nodes":[[["b","aa"]]]}]}`,
+		`{"trees":[{"nodes":[[["B","aa"]]],"children":[null]}]}`,
+		`{"trees":[{"nodes":[{"B":"aa"}]}]}`,
+	} {
+		if _, err := ParseJSON(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: no error", in)
+		}
+	}
+}
@@ -52,3 +52,120 @@ func TestBasic(t *testing.T) {
 			expect, names)
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	_, err := ParseSGF(strings.NewReader("(;FF[4]\n  ;B[aa]x;W[bb])"))
+	pe, ok := err.(ParseError)
+	if !ok || len(pe) != 1 {
+		t.Fatalf("expected one parse error, got %#v", err)
+	}
+	want := Pos{Offset: 16, Line: 2, Column: 9}
+	if pe[0].Pos != want {
+		t.Errorf("pos=%+v want %+v", pe[0].Pos, want)
+	}
+	if pe[0].Error() != "2:9: Unexpected character `x'" {
+		t.Errorf("msg=%q", pe[0].Error())
+	}
+}
+
+func TestLenient(t *testing.T) {
+	cases := []struct {
+		in       string
+		trees    int
+		nodes    []int
+		comment  string
+		warnings int
+	}{
+		{"(;FF[4];B[aa];W[bb])", 1, []int{3}, "", 0},
+		{"Subject: game\n(;FF[4];B[aa];W[bb]", 1, []int{3}, "", 2},
+		{"(;FF[4];B[aa] garbage ;W[bb])", 1, []int{3}, "", 1},
+		{"(;FF[4]C[see [a] here];B[aa])", 1, []int{2}, "see [a] here", 1},
+		{"(;FF[4]C[see [A] Then];B[aa])", 1, []int{2}, "see [A] Then", 1},
+		{"(FF[4];B[aa])(;B[bb]", 2, []int{2, 1}, "", 2},
+		{"(;FF[4]B;W[bb])", 1, []int{2}, "", 1},
+		{"(;FF[4];B[aa]))(;B[cc])", 2, []int{2, 1}, "", 1},
+		{"(;FF[4](;B[aa](;W[bb]", 1, []int{1}, "", 1},
+	}
+	for _, tc := range cases {
+		if _, err := ParseSGF(strings.NewReader(tc.in)); tc.warnings > 0 && err == nil {
+			t.Errorf("%q: strict parse succeeded", tc.in)
+		}
+		c, warns, err := ParseSGFOptions(strings.NewReader(tc.in), Options{Lenient: true})
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if len(warns) != tc.warnings {
+			t.Errorf("%q: warnings=%v", tc.in, warns)
+		}
+		if len(c.Trees) != tc.trees {
+			t.Errorf("%q: %d trees", tc.in, len(c.Trees))
+			continue
+		}
+		for i, n := range tc.nodes {
+			if got := len(c.Trees[i].Principal.Nodes); got != n {
+				t.Errorf("%q: tree %d: %d nodes", tc.in, i, got)
+			}
+		}
+		if tc.comment != "" {
+			if v, _ := c.Trees[0].Principal.Nodes[0].Value("C"); string(v) != tc.comment {
+				t.Errorf("%q: C=%q", tc.in, v)
+			}
+		}
+	}
+}
+
+func TestCompat(t *testing.T) {
+	const ff3 = `(;FF[3]GaMe[1]SiZe[9]BS[0]WS[0]
+ ;AddBlack[aa][bb]AddWhite[cc]L[dd][ee]M[ff]
+ ;Black[gg]CH[1];White[hh])`
+	if _, err := ParseSGF(strings.NewReader(ff3)); err == nil {
+		t.Errorf("strict parse accepted FF[3] identifiers")
+	}
+	c, warns, err := ParseSGFOptions(strings.NewReader(ff3), Options{Compat: true})
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	var got []string
+	for _, n := range c.Trees[0].Principal.Nodes {
+		var props []string
+		for _, p := range n.Props {
+			var vs []string
+			for _, v := range p.Values {
+				vs = append(vs, string(v))
+			}
+			props = append(props, p.Prop+"["+strings.Join(vs, "][")+"]")
+		}
+		got = append(got, strings.Join(props, ""))
+	}
+	want := []string{
+		"FF[4]GM[1]SZ[9]",
+		"AB[aa][bb]AW[cc]LB[dd:A][ee:B]MA[ff]",
+		"B[gg]",
+		"W[hh]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+	var msgs []string
+	for _, w := range warns {
+		msgs = append(msgs, w.Msg)
+	}
+	expect := []string{
+		"Converted FF[3] to FF[4]",
+		"Converted GaMe to GM",
+		"Converted SiZe to SZ",
+		"Dropped obsolete property BS",
+		"Dropped obsolete property WS",
+		"Converted AddBlack to AB",
+		"Converted AddWhite to AW",
+		"Converted obsolete property L to LB",
+		"Converted obsolete property M to MA",
+		"Converted Black to B",
+		"Dropped obsolete property CH",
+		"Converted White to W",
+	}
+	if !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("got %#v want %#v", msgs, expect)
+	}
+}
@@ -0,0 +1,160 @@
+package sgf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxBoardSize is the largest board dimension representable in SGF
+// point notation
+const MaxBoardSize = 52
+
+// DefaultBoardSize is the board size implied for Go when a game has
+// no SZ property
+const DefaultBoardSize = 19
+
+// Get returns the first property named `prop` in the node, or nil if
+// there is none
+func (n *Node) Get(prop string) *Property {
+	for i := range n.Props {
+		if n.Props[i].Prop == prop {
+			return &n.Props[i]
+		}
+	}
+	return nil
+}
+
+// Value returns the first value of property `prop` and whether the
+// property is present
+func (n *Node) Value(prop string) (PropValue, bool) {
+	p := n.Get(prop)
+	if p == nil || len(p.Values) == 0 {
+		return "", false
+	}
+	return p.Values[0], true
+}
+
+// Number interprets the value as an SGF Number
+func (v PropValue) Number() (int, error) {
+	return strconv.Atoi(strings.TrimSpace(string(v)))
+}
+
+// Real interprets the value as an SGF Real
+func (v PropValue) Real() (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+}
+
+// Compose splits an SGF Compose value `a:b` into its two halves. ok
+// is false if the value is not a composed value.
+func (v PropValue) Compose() (a, b PropValue, ok bool) {
+	i := strings.IndexByte(string(v), ':')
+	if i < 0 {
+		return v, "", false
+	}
+	return v[:i], v[i+1:], true
+}
+
+// Point interprets the value as a Go point, returning zero-based
+// column and row. An empty value (an FF[4] pass) returns -1,-1.
+func (v PropValue) Point() (x, y int, err error) {
+	if len(v) == 0 {
+		return -1, -1, nil
+	}
+	if len(v) != 2 {
+		return 0, 0, fmt.Errorf("bad point: %q", string(v))
+	}
+	x, y = pointCoord(v[0]), pointCoord(v[1])
+	if x < 0 || y < 0 {
+		return 0, 0, fmt.Errorf("bad point: %q", string(v))
+	}
+	return x, y, nil
+}
+
+// PointRange interprets the value as an FF[4] compressed point list
+// entry, which is either a single point or a rectangle `ul:lr`. It
+// returns the inclusive bounds of the rectangle.
+func (v PropValue) PointRange() (x0, y0, x1, y1 int, err error) {
+	a, b, ok := v.Compose()
+	if !ok {
+		b = a
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("bad point: %q", string(v))
+	}
+	if x0, y0, err = a.Point(); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if x1, y1, err = b.Point(); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if x1 < x0 {
+		x0, x1 = x1, x0
+	}
+	if y1 < y0 {
+		y0, y1 = y1, y0
+	}
+	return x0, y0, x1, y1, nil
+}
+
+func pointCoord(c byte) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 26
+	default:
+		return -1
+	}
+}
+
+func pointLetter(i int) byte {
+	if i < 26 {
+		return byte('a' + i)
+	}
+	return byte('A' + i - 26)
+}
+
+// PointValue returns the SGF encoding of the point at column x, row
+// y. A pass (-1,-1) is encoded as the empty value.
+func PointValue(x, y int) PropValue {
+	if x < 0 && y < 0 {
+		return ""
+	}
+	return PropValue([]byte{pointLetter(x), pointLetter(y)})
+}
+
+// BoardSize returns the board dimensions declared by a root node's SZ
+// property, which may be either `SZ[n]` or `SZ[columns:rows]`.
+func (n *Node) BoardSize() (width, height int, err error) {
+	v, ok := n.Value("SZ")
+	if !ok {
+		return DefaultBoardSize, DefaultBoardSize, nil
+	}
+	if c, r, ok := v.Compose(); ok {
+		if width, err = c.Number(); err != nil {
+			return 0, 0, fmt.Errorf("bad SZ: %q", string(v))
+		}
+		if height, err = r.Number(); err != nil {
+			return 0, 0, fmt.Errorf("bad SZ: %q", string(v))
+		}
+	} else {
+		if width, err = v.Number(); err != nil {
+			return 0, 0, fmt.Errorf("bad SZ: %q", string(v))
+		}
+		height = width
+	}
+	if width < 1 || height < 1 ||
+		width > MaxBoardSize || height > MaxBoardSize {
+		return 0, 0, fmt.Errorf("bad SZ: %q", string(v))
+	}
+	return width, height, nil
+}
+
+// SizeValue returns the SZ value for a board of the given dimensions
+func SizeValue(width, height int) PropValue {
+	if width == height {
+		return PropValue(strconv.Itoa(width))
+	}
+	return PropValue(fmt.Sprintf("%d:%d", width, height))
+}
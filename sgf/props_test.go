@@ -0,0 +1,193 @@
+package sgf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBoardSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		w, h int
+		err  bool
+	}{
+		{"(;FF[4])", 19, 19, false},
+		{"(;SZ[9])", 9, 9, false},
+		{"(;SZ[19:13])", 19, 13, false},
+		{"(;SZ[52:3])", 52, 3, false},
+		{"(;SZ[53])", 0, 0, true},
+		{"(;SZ[0:5])", 0, 0, true},
+		{"(;SZ[x])", 0, 0, true},
+	}
+	for _, tc := range cases {
+		c, err := ParseSGF(strings.NewReader(tc.in))
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.in, err)
+		}
+		w, h, err := c.Trees[0].Principal.Nodes[0].BoardSize()
+		if (err != nil) != tc.err {
+			t.Errorf("%s: err=%v", tc.in, err)
+			continue
+		}
+		if w != tc.w || h != tc.h {
+			t.Errorf("%s: got %dx%d want %dx%d", tc.in, w, h, tc.w, tc.h)
+		}
+	}
+}
+
+func TestPoint(t *testing.T) {
+	for x := 0; x < MaxBoardSize; x++ {
+		for y := 0; y < MaxBoardSize; y++ {
+			gx, gy, err := PointValue(x, y).Point()
+			if err != nil || gx != x || gy != y {
+				t.Errorf("Point(PointValue(%d,%d)) = %d,%d,%v",
+					x, y, gx, gy, err)
+			}
+		}
+	}
+	if x, y, err := PropValue("").Point(); err != nil || x != -1 || y != -1 {
+		t.Errorf("pass: %d,%d,%v", x, y, err)
+	}
+	if _, _, err := PropValue("a1").Point(); err == nil {
+		t.Errorf("bad point accepted")
+	}
+	x0, y0, x1, y1, err := PropValue("dc:ba").PointRange()
+	if err != nil || x0 != 1 || y0 != 0 || x1 != 3 || y1 != 2 {
+		t.Errorf("PointRange = %d,%d,%d,%d,%v", x0, y0, x1, y1, err)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	c, err := ParseSGF(strings.NewReader(basic))
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	c.Trees[0].Principal.Nodes[0].Props = append(
+		c.Trees[0].Principal.Nodes[0].Props,
+		Property{"GC", []PropValue{`a]b\c`}})
+	var buf bytes.Buffer
+	if err := WriteSGF(&buf, c); err != nil {
+		t.Fatal("write:", err)
+	}
+	c2, err := ParseSGF(&buf)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(c, c2) {
+		t.Errorf("round trip mismatch:\n%s", buf.String())
+	}
+}
+
+func TestCharset(t *testing.T) {
+	latin1 := "(;FF[4]CA[ISO-8859-1]PB[Jos\xe9];B[aa])"
+	c, err := ParseSGF(strings.NewReader(latin1))
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	if v, _ := c.Trees[0].Principal.Nodes[0].Value("PB"); v != "José" {
+		t.Errorf("PB=%q", v)
+	}
+	var buf bytes.Buffer
+	if err := WriteSGF(&buf, c); err != nil {
+		t.Fatal("write:", err)
+	}
+	if !strings.Contains(buf.String(), "CA[UTF-8]PB[José]") {
+		t.Errorf("bad output: %q", buf.String())
+	}
+
+	RegisterCharset("X-ROT13", func(r io.Reader) io.Reader {
+		return rot13{r}
+	})
+	c, err = ParseSGF(strings.NewReader("\xef\xbb\xbf(;CA[x-rot13]C[uryyb])"))
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	if v, _ := c.Trees[0].Principal.Nodes[0].Value("C"); v != "hello" {
+		t.Errorf("C=%q", v)
+	}
+
+	koi8 := "(;CA[KOI8-R]PB[\xf0\xd2])"
+	c, warns, err := ParseSGFOptions(strings.NewReader(koi8), Options{})
+	if err != nil || len(warns) != 1 {
+		t.Errorf("unknown charset: warns=%v err=%v", warns, err)
+	}
+	// The text was not decoded, so its CA is kept
+	buf.Reset()
+	WriteSGF(&buf, c)
+	if buf.String() != koi8+"\n" {
+		t.Errorf("unknown charset output: %q", buf.String())
+	}
+
+	c, _, err = ParseSGFOptions(strings.NewReader("(;C[\xe9])"), Options{Charset: "latin1"})
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	if v, _ := c.Trees[0].Principal.Nodes[0].Value("C"); v != "é" {
+		t.Errorf("C=%q", v)
+	}
+	buf.Reset()
+	WriteSGF(&buf, c)
+	if buf.String() != "(;C[é]CA[UTF-8])\n" {
+		t.Errorf("bad output: %q", buf.String())
+	}
+}
+
+func TestCharsetFixtures(t *testing.T) {
+	cases := []struct {
+		file      string
+		pb, pw, c string
+		moveC     string
+	}{
+		{"gb2312.sgf", "常昊", "古力", "黑中盘胜", "星位"},
+		// Several of these characters have `\' or `]' as their
+		// second byte
+		{"shift_jis.sgf", "井山裕太", "張栩", "表ソ十能予ゾ [A]", "ｶﾀｶﾅ"},
+		{"euc-kr.sgf", "이창호", "조훈현", "흑 불계승", "화점"},
+	}
+	for _, tc := range cases {
+		f, err := os.Open(filepath.Join("testdata", tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ParseSGF(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		nodes := c.Trees[0].Principal.Nodes
+		for _, p := range []struct{ prop, want string }{{"PB", tc.pb}, {"PW", tc.pw}, {"C", tc.c}} {
+			if v, _ := nodes[0].Value(p.prop); string(v) != p.want {
+				t.Errorf("%s: %s=%q, want %q", tc.file, p.prop, v, p.want)
+			}
+		}
+		if v, _ := nodes[1].Value("C"); string(v) != tc.moveC {
+			t.Errorf("%s: move C=%q, want %q", tc.file, v, tc.moveC)
+		}
+		var buf bytes.Buffer
+		if err := WriteSGF(&buf, c); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "CA[UTF-8]") {
+			t.Errorf("%s: output %q", tc.file, buf.String())
+		}
+	}
+}
+
+// rot13 is a toy character set that rotates lowercase letters
+type rot13 struct{ r io.Reader }
+
+func (r rot13) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i, b := range p[:n] {
+		if b >= 'a' && b <= 'z' {
+			p[i] = 'a' + (b-'a'+13)%26
+		}
+	}
+	return n, err
+}
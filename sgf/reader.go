@@ -0,0 +1,71 @@
+package sgf
+
+import "io"
+
+// Reader reads the game trees of an SGF collection one at a time, so
+// that large collections can be processed without holding them in
+// memory.
+type Reader struct {
+	// SkipVariations causes only the main line of each game tree
+	// to be returned. Other variations are discarded as they are
+	// read, without being parsed.
+	SkipVariations bool
+
+	l        *lexer
+	warnings ParseError
+}
+
+// NewReader returns a Reader that reads SGF from in with the provided
+// options. The character set is determined from the first game tree,
+// as for ParseSGFOptions.
+func NewReader(in io.Reader, opts Options) *Reader {
+	return &Reader{l: newLexer(in, opts)}
+}
+
+// Next returns the next top-level game tree in the collection, or
+// io.EOF at the end of input. A ParseError is returned for a game
+// tree that could not be parsed; reading may continue with the
+// following tree. In lenient mode, damaged trees are skipped instead.
+func (r *Reader) Next() (*GameTree, error) {
+	r.l.skipVariations = r.SkipVariations
+	r.warnings = nil
+	for {
+		more := r.l.parseTree()
+		r.warnings = append(r.warnings, r.l.warnings...)
+		r.l.warnings = nil
+		if r.l.ioErr != nil && r.l.ioErr != io.EOF {
+			return nil, r.l.ioErr
+		}
+		if len(r.l.errs) > 0 {
+			errs := r.l.errs
+			r.l.errs = nil
+			return nil, errs
+		}
+		if !more {
+			return nil, io.EOF
+		}
+		if r.l.c == nil {
+			continue
+		}
+		t := r.l.c.Trees[0]
+		r.l.c = nil
+		if r.SkipVariations {
+			flatten(t)
+		}
+		return t, nil
+	}
+}
+
+// Warnings returns the warnings produced while reading the game tree
+// most recently returned by Next
+func (r *Reader) Warnings() ParseError {
+	return r.warnings
+}
+
+// flatten merges the chain of single children left by skipping
+// variations into t's principal sequence
+func flatten(t *GameTree) {
+	for len(t.Children) == 1 {
+		normalize(t)
+	}
+}
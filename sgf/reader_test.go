@@ -0,0 +1,129 @@
+package sgf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	in := basic + "\n(;FF[4];B[aa](;W[bb];B[cc])(;W[cc]))" + "\n(;B[aa]x)" + "(;B[dd])"
+	r := NewReader(strings.NewReader(in), Options{})
+	var trees []*GameTree
+	var errs int
+	for {
+		tree, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(ParseError); ok {
+			errs++
+			continue
+		}
+		if err != nil {
+			t.Fatal("next:", err)
+		}
+		trees = append(trees, tree)
+	}
+	if len(trees) != 3 || errs != 1 {
+		t.Fatalf("read %d trees, %d errors", len(trees), errs)
+	}
+	if got := sgfString(trees[1]); got != "(;FF[4];B[aa](;W[bb];B[cc])(;W[cc]))" {
+		t.Errorf("tree 1: %s", got)
+	}
+
+	r = NewReader(strings.NewReader(in), Options{Lenient: true})
+	r.SkipVariations = true
+	trees = nil
+	for {
+		tree, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("next:", err)
+		}
+		if want := len(trees) == 2; want != (len(r.Warnings()) == 1) {
+			t.Errorf("tree %d: warnings: %v", len(trees), r.Warnings())
+		}
+		trees = append(trees, tree)
+	}
+	if len(trees) != 4 {
+		t.Fatalf("read %d trees", len(trees))
+	}
+	if got := sgfString(trees[1]); got != "(;FF[4];B[aa];W[bb];B[cc])" {
+		t.Errorf("tree 1: %s", got)
+	}
+	var moves int
+	for c := NewCursor(trees[0]); c.Next(); moves++ {
+		if len(c.Variations()) > 1 {
+			t.Fatalf("variation at %s", c.Path())
+		}
+	}
+	if moves != 5 || len(trees[0].Children) != 0 {
+		t.Errorf("main line has %d moves", moves)
+	}
+}
+
+// syntheticCollection generates a collection of `games` game trees of
+// `moves` moves each, with a variation every tenth move, without
+// holding it in memory
+type syntheticCollection struct {
+	games, moves int
+	game         int
+	buf          bytes.Buffer
+}
+
+func (s *syntheticCollection) Read(p []byte) (int, error) {
+	for s.buf.Len() < len(p) && s.game < s.games {
+		fmt.Fprintf(&s.buf, "(;FF[4]GM[1]SZ[19]PB[Black %d]PW[White %d]RE[B+R]\n", s.game, s.game)
+		for i := 0; i < s.moves; i++ {
+			c := "B"
+			if i%2 == 1 {
+				c = "W"
+			}
+			pt := PointValue((i*7+s.game)%19, (i*11)%19)
+			if i%10 == 9 {
+				fmt.Fprintf(&s.buf, "(;%s[%s]C[alternative])(", c, pt)
+			}
+			fmt.Fprintf(&s.buf, ";%s[%s]", c, pt)
+		}
+		for i := 9; i < s.moves; i += 10 {
+			s.buf.WriteString(")")
+		}
+		s.buf.WriteString(")\n")
+		s.game++
+	}
+	return s.buf.Read(p)
+}
+
+func benchmarkReader(b *testing.B, skip bool) {
+	for i := 0; i < b.N; i++ {
+		r := NewReader(&syntheticCollection{games: 1000, moves: 200}, Options{})
+		r.SkipVariations = skip
+		n := 0
+		for {
+			_, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			n++
+		}
+		if n != 1000 {
+			b.Fatalf("read %d games", n)
+		}
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	benchmarkReader(b, false)
+}
+
+func BenchmarkReaderSkipVariations(b *testing.B) {
+	benchmarkReader(b, true)
+}
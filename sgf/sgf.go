@@ -17,43 +17,219 @@ import (
 
 const eof = 0
 
+// maxLookahead bounds how far ahead the lexer will look when deciding
+// how to recover from damaged input
+const maxLookahead = 4096
+
+// Pos represents a position in SGF input. Offset is a zero-based
+// byte offset; Line and Column are one-based, and Column counts
+// runes.
+type Pos struct {
+	Offset, Line, Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SyntaxError describes a single problem found in SGF input
+type SyntaxError struct {
+	Pos
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ParseError represents one or more parse errors parsing an SGF file
+type ParseError []*SyntaxError
+
+func (p ParseError) Error() string {
+	var msgs []string
+	for _, e := range p {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Options controls optional parser behavior
+type Options struct {
+	// Lenient enables recovery from common damage in real-world
+	// files: text outside of game trees or between nodes, missing
+	// closing parentheses, missing `;' node markers, properties
+	// without values and unescaped `]' in property values. Problems
+	// that were recovered from are reported as warnings rather than
+	// errors.
+	Lenient bool
+
+	// Charset is the character set assumed for input whose first
+	// game tree does not declare one with a CA property. If it is
+	// empty, such input is read as UTF-8. Text is converted to UTF-8
+	// as it is parsed, so Pos offsets count bytes of UTF-8.
+	Charset string
+
+	// Compat enables compatibility with FF[1]-FF[3] files: property
+	// identifiers containing lowercase letters, such as AddBlack, are
+	// reduced to their uppercase letters as in FF[4], and properties
+	// that FF[4] removed are converted or dropped. Each distinct
+	// conversion is reported once as a warning.
+	Compat bool
+}
+
+type srcRune struct {
+	r    rune
+	size int
+}
+
 type lexer struct {
-	c        *Collection
-	unread   rune
-	r        io.RuneReader
-	ioErr    error
-	parseErr []string
+	opts  Options
+	c     *Collection
+	r     io.RuneReader
+	ioErr error
+	// undecoded is set when the input is in a character set with no
+	// registered decoder, and its non-UTF-8 bytes are passed through
+	// as they are
+	undecoded bool
+
+	// buf[bufStart:] holds runes that have been read but not yet
+	// consumed
+	buf      []srcRune
+	bufStart int
+	// pos is the position of the next unconsumed rune
+	pos Pos
+	// tokPos is the position of the most recently lexed token
+	tokPos Pos
+
+	// depth is the current game tree nesting depth
+	depth int
+	// last is the last token returned to the parser
+	last int
+	// treeDone is set when a top-level game tree has been closed,
+	// and causes Lex to report the end of input until it is reset
+	treeDone bool
+	// sawEOF is set once a missing `)' at end of input has been
+	// reported
+	sawEOF bool
+	// pending holds a token to be returned on the next call to
+	// Lex, after a synthesized token
+	pending    int
+	pendingVal yySymType
+
+	// propFrom is the FF[3] identifier of the property whose values
+	// are being lexed, before any conversion, and valIdx counts
+	// its values so far. They are only maintained in Compat mode.
+	propFrom  string
+	valIdx    int
+	converted map[string]bool
+
+	// skipVariations causes all but the first child of each game
+	// tree to be discarded unparsed. children counts, for each open
+	// game tree, the child trees seen so far.
+	skipVariations bool
+	children       []int
+
+	errs     ParseError
+	warnings ParseError
+}
+
+func newLexer(in io.Reader, opts Options) *lexer {
+	l := &lexer{
+		opts: opts,
+		pos:  Pos{Line: 1, Column: 1},
+		last: -1,
+	}
+	br := bufio.NewReader(decodeInput(in, opts, l))
+	if l.undecoded {
+		l.r = rawRuneReader{br}
+	} else {
+		l.r = br
+	}
+	return l
+}
+
+// peek returns the rune `i` positions ahead of the next unconsumed
+// rune without consuming anything
+func (l *lexer) peek(i int) rune {
+	for len(l.buf)-l.bufStart <= i {
+		if l.ioErr != nil {
+			return eof
+		}
+		r, size, err := l.r.ReadRune()
+		if err != nil {
+			l.ioErr = err
+			return eof
+		}
+		l.buf = append(l.buf, srcRune{r, size})
+	}
+	return l.buf[l.bufStart+i].r
 }
 
 func (l *lexer) next() rune {
-	if l.unread != 0 {
-		r := l.unread
-		l.unread = 0
-		return r
-	}
-	r, _, err := l.r.ReadRune()
-	if err != nil {
-		l.ioErr = err
+	if l.peek(0) == eof && l.bufStart == len(l.buf) {
 		return eof
 	}
-	return r
+	sr := l.buf[l.bufStart]
+	l.bufStart++
+	if l.bufStart == len(l.buf) {
+		l.buf, l.bufStart = l.buf[:0], 0
+	}
+	l.pos.Offset += sr.size
+	if sr.r == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+	return sr.r
 }
 
-func (l *lexer) putback(r rune) {
-	if l.unread != 0 {
-		panic("double putback")
+func isSpace(r rune) bool {
+	switch r {
+	case '\v', ' ', '\t', '\r', '\n':
+		return true
 	}
-	l.unread = r
+	return false
 }
 
-func (l *lexer) Lex(lval *yySymType) int {
+func isUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+func isLetter(r rune) bool {
+	return isUpper(r) || (r >= 'a' && r <= 'z')
+}
+
+func (l *lexer) errorAt(pos Pos, msg string) {
+	l.errs = append(l.errs, &SyntaxError{pos, msg})
+}
+
+func (l *lexer) warnAt(pos Pos, msg string) {
+	l.warnings = append(l.warnings, &SyntaxError{pos, msg})
+}
+
+// recover reports a problem that lenient mode is able to repair
+func (l *lexer) recover(pos Pos, msg string) {
+	if l.opts.Lenient {
+		l.warnAt(pos, msg)
+	} else {
+		l.errorAt(pos, msg)
+	}
+}
+
+// scan returns the next raw token from the input
+func (l *lexer) scan(lval *yySymType) int {
 	for {
+		l.tokPos = l.pos
 		r := l.next()
 		if r == eof {
 			return eof
 		}
-		if r >= 'A' && r <= 'Z' {
-			return l.lexPropName(lval, r)
+		if isUpper(r) || (l.opts.Compat && isLetter(r)) {
+			if tok := l.lexPropName(lval, r); tok != eof {
+				return tok
+			}
+			continue
 		}
 		switch r {
 		case '(', ')', ';':
@@ -62,25 +238,49 @@ func (l *lexer) Lex(lval *yySymType) int {
 			return l.lexPropVal(lval)
 		case '\v', ' ', '\t', '\r', '\n':
 		default:
-			l.Error(fmt.Sprintf("Unexpected character `%c'", r))
+			l.lexJunk(r)
 		}
 	}
 }
 
-func (l *lexer) lexPropName(lval *yySymType, r rune) int {
+// lexJunk consumes a run of unexpected characters starting with r
+func (l *lexer) lexJunk(r rune) {
 	rs := []rune{r}
-	for {
-		r := l.next()
-		if r == eof {
+	for l.opts.Lenient {
+		r := l.peek(0)
+		if r == eof || isSpace(r) || isUpper(r) ||
+			strings.ContainsRune("()[;", r) {
 			break
 		}
-		if r < 'A' || r > 'Z' {
-			l.putback(r)
+		rs = append(rs, l.next())
+	}
+	if len(rs) == 1 {
+		l.recover(l.tokPos, fmt.Sprintf("Unexpected character `%c'", r))
+	} else {
+		l.recover(l.tokPos, fmt.Sprintf("Unexpected text `%s'", string(rs)))
+	}
+}
+
+// lexPropName lexes a property identifier. In Compat mode it may
+// discard the property, in which case it returns eof.
+func (l *lexer) lexPropName(lval *yySymType, r rune) int {
+	rs := []rune{r}
+	for {
+		r := l.peek(0)
+		if !isUpper(r) && !(l.opts.Compat && isLetter(r)) {
 			break
 		}
-		rs = append(rs, r)
+		rs = append(rs, l.next())
 	}
 	lval.name = string(rs)
+	if l.opts.Compat {
+		var ok bool
+		l.propFrom, l.valIdx = "", 0
+		if lval.name, ok = l.compatName(lval.name); !ok {
+			l.skipValues()
+			return eof
+		}
+	}
 	return TokPropName
 }
 
@@ -88,12 +288,17 @@ func (l *lexer) lexPropVal(lval *yySymType) int {
 	var b bytes.Buffer
 L:
 	for {
+		pos := l.pos
 		r := l.next()
 		switch r {
 		case eof:
+			l.recover(pos, "Unterminated property value")
 			break L
 		case ']':
-			break L
+			if !l.opts.Lenient || l.closesValue() {
+				break L
+			}
+			l.warnAt(pos, "Unescaped `]' in property value")
 		case '\\':
 			rr := l.next()
 			switch rr {
@@ -104,37 +309,278 @@ L:
 			}
 			r = rr
 		}
+		if c, ok := isRawByte(r); ok {
+			b.WriteByte(c)
+			continue
+		}
 		b.WriteRune(r)
 	}
 	lval.v = PropValue(b.String())
+	if l.opts.Compat {
+		lval.v = l.compatValue(lval.v)
+	}
 	return TokPropValue
 }
 
-func (l *lexer) Error(s string) {
-	l.parseErr = append(l.parseErr, s)
+// closesValue reports whether a `]' that has just been consumed
+// plausibly ends a property value. It does unless the text following
+// it runs into another `]' before reaching anything that looks like
+// SGF structure.
+func (l *lexer) closesValue() bool {
+	i := 0
+	for isSpace(l.peek(i)) {
+		i++
+	}
+	if isLetter(l.peek(i)) {
+		j := i
+		for isLetter(l.peek(j)) {
+			j++
+		}
+		for isSpace(l.peek(j)) {
+			j++
+		}
+		if l.peek(j) == '[' {
+			return true
+		}
+	}
+	for ; i < maxLookahead; i++ {
+		switch l.peek(i) {
+		case eof, '[', '(', ')', ';':
+			return true
+		case '\\':
+			i++
+		case ']':
+			return false
+		}
+	}
+	return true
 }
 
-// ParseError represents one or more parse errors parsing an SGF file
-type ParseError []string
+// skipToTree discards input up to the start of the next game tree,
+// returning false if there is none
+func (l *lexer) skipToTree() bool {
+	var junk bool
+	pos := l.pos
+	for {
+		r := l.peek(0)
+		if r == eof {
+			break
+		}
+		if r == '(' {
+			break
+		}
+		if !isSpace(r) {
+			if !l.opts.Lenient {
+				l.tokPos = l.pos
+				l.next()
+				l.lexJunk(r)
+				continue
+			}
+			junk = true
+		}
+		l.next()
+	}
+	if junk {
+		l.warnAt(pos, "Ignoring text outside of game tree")
+	}
+	return l.peek(0) == '('
+}
 
-func (p ParseError) Error() string {
-	return strings.Join([]string(p), "\n")
+// skipTree discards the remainder of the current top-level game tree
+// after a syntax error
+func (l *lexer) skipTree() {
+	var lval yySymType
+	for l.depth > 0 {
+		switch l.scan(&lval) {
+		case eof:
+			l.depth = 0
+		case '(':
+			l.depth++
+		case ')':
+			l.depth--
+		}
+	}
+}
+
+// Lex implements yyLexer. In lenient mode it repairs the raw token
+// stream so that it matches the grammar.
+func (l *lexer) Lex(lval *yySymType) int {
+	if l.pending != 0 {
+		tok := l.pending
+		*lval = l.pendingVal
+		l.pending = 0
+		return l.emit(tok)
+	}
+	for {
+		if l.treeDone {
+			return eof
+		}
+		tok := l.scan(lval)
+		if tok == '(' && l.skipVariation() {
+			continue
+		}
+		if !l.opts.Lenient {
+			return l.emit(tok)
+		}
+		switch tok {
+		case eof:
+			if l.depth == 0 {
+				return eof
+			}
+			if !l.sawEOF {
+				l.warnAt(l.tokPos, "Missing `)' at end of input")
+				l.sawEOF = true
+			}
+			tok = ')'
+		case TokPropName:
+			if l.peekValue() {
+				break
+			}
+			l.warnAt(l.tokPos, fmt.Sprintf("Property %s has no value", lval.name))
+			continue
+		}
+		switch {
+		case l.last == '(' && tok != ';':
+			l.warnAt(l.tokPos, "Missing `;' at start of game tree")
+			l.pending, l.pendingVal = tok, *lval
+			return l.emit(';')
+		case tok == TokPropName && l.last != ';' && l.last != TokPropValue:
+			l.warnAt(l.tokPos, fmt.Sprintf("Property %s outside of a node", lval.name))
+			l.skipValues()
+			continue
+		case tok == TokPropValue && l.last != TokPropName && l.last != TokPropValue:
+			l.warnAt(l.tokPos, "Property value without a property name")
+			continue
+		}
+		return l.emit(tok)
+	}
+}
+
+// emit records tok as having been returned to the parser
+func (l *lexer) emit(tok int) int {
+	switch tok {
+	case '(':
+		if l.depth > 0 {
+			l.children[l.depth-1]++
+		}
+		l.children = append(l.children[:l.depth], 0)
+		l.depth++
+	case ')':
+		l.depth--
+		if l.depth >= 0 {
+			l.children = l.children[:l.depth]
+		}
+		if l.depth == 0 {
+			l.treeDone = true
+		}
+	}
+	l.last = tok
+	return tok
+}
+
+// skipVariation is called after lexing a `(', and discards the game
+// tree it opens if it is not the first child of its parent and
+// variations are being skipped
+func (l *lexer) skipVariation() bool {
+	if !l.skipVariations || l.depth == 0 || l.children[l.depth-1] == 0 {
+		return false
+	}
+	var lval yySymType
+	for depth := 1; depth > 0; {
+		switch l.scan(&lval) {
+		case eof:
+			return true
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return true
+}
+
+// peekValue reports whether the next token will be a property value
+func (l *lexer) peekValue() bool {
+	i := 0
+	for isSpace(l.peek(i)) {
+		i++
+	}
+	return l.peek(i) == '['
+}
+
+// skipValues discards any property values that follow
+func (l *lexer) skipValues() {
+	var lval yySymType
+	for l.peekValue() {
+		for isSpace(l.peek(0)) {
+			l.next()
+		}
+		l.next()
+		l.lexPropVal(&lval)
+	}
+}
+
+func (l *lexer) Error(s string) {
+	l.recover(l.tokPos, s)
+}
+
+// parseTree parses the next top-level game tree from the input into
+// l.c, returning false at the end of input
+func (l *lexer) parseTree() bool {
+	if !l.skipToTree() {
+		return false
+	}
+	l.c = nil
+	l.treeDone = false
+	l.pending = 0
+	l.last = -1
+	l.depth = 0
+	l.children = l.children[:0]
+	if yyNewParser().Parse(l) != 0 {
+		if l.opts.Lenient {
+			l.warnAt(l.tokPos, "Discarding damaged game tree")
+		}
+		l.c = nil
+		l.skipTree()
+	}
+	return true
 }
 
 // ParseSGF parses an SGF file from the provided reader and returns it
 // in tree form
 func ParseSGF(in io.Reader) (*Collection, error) {
-	l := &lexer{
-		r: bufio.NewReader(in),
+	c, _, err := ParseSGFOptions(in, Options{})
+	return c, err
+}
+
+// ParseSGFOptions parses an SGF file with the provided options. The
+// character set of the input is taken from the CA property of the
+// first game tree's root node, if present, and text is decoded to
+// UTF-8 using the decoder registered for it with RegisterCharset. The
+// same character set is used for every game tree in the input, so a
+// collection whose trees declare different character sets should be
+// split before it is parsed. Text in a character set with no
+// registered decoder is left as it is, with a warning. In
+// lenient mode, it returns whatever portion of the collection could be
+// recovered together with a list of warnings describing the repairs
+// that were made; a non-nil error is returned only for I/O errors or
+// input that contains no game trees at all.
+func ParseSGFOptions(in io.Reader, opts Options) (*Collection, ParseError, error) {
+	l := newLexer(in, opts)
+	out := &Collection{}
+	for l.parseTree() {
+		if l.c != nil {
+			out.Trees = append(out.Trees, l.c.Trees...)
+		}
 	}
-	p := yyNewParser()
-	p.Parse(l)
 	if l.ioErr != nil && l.ioErr != io.EOF {
-		return nil, l.ioErr
+		return nil, l.warnings, l.ioErr
 	}
-
-	if len(l.parseErr) > 0 {
-		return nil, ParseError(l.parseErr)
+	if len(out.Trees) == 0 && len(l.errs) == 0 {
+		l.errorAt(l.pos, "No game trees found")
+	}
+	if len(l.errs) > 0 {
+		return nil, l.warnings, l.errs
 	}
-	return l.c, nil
+	return out, l.warnings, nil
 }
@@ -1,5 +1,7 @@
 package sgf
 
+import "fmt"
+
 // Collection represents an sgf `Collection` object
 type Collection struct {
 	Trees []*GameTree
@@ -34,3 +36,20 @@ type Property struct {
 // methods are provided to interpret properties in the standad SGF
 // formats.
 type PropValue string
+
+// Path identifies a node within a GameTree. Variations lists the index
+// of the child chosen at each branch point, starting from the root
+// GameTree, and Node is the index of the node within the principal
+// sequence of the GameTree so reached.
+type Path struct {
+	Variations []int
+	Node       int
+}
+
+func (p Path) String() string {
+	s := ""
+	for _, v := range p.Variations {
+		s += fmt.Sprintf("%d.", v)
+	}
+	return fmt.Sprintf("%s%d", s, p.Node)
+}
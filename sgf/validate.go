@@ -0,0 +1,453 @@
+package sgf
+
+import "fmt"
+
+// Severity classifies a validation Diagnostic
+type Severity int
+
+const (
+	// SeverityWarning indicates input that is legal but suspicious, or
+	// that is commonly accepted despite violating the specification
+	SeverityWarning Severity = iota
+	// SeverityError indicates a violation of the specification
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic describes a problem found by Validate
+type Diagnostic struct {
+	Severity Severity
+	// Tree is the index of the game tree within the collection
+	Tree int
+	// Path locates the offending node within the game tree
+	Path Path
+	// Prop is the offending property, if any
+	Prop string
+	Msg  string
+}
+
+func (d *Diagnostic) String() string {
+	where := fmt.Sprintf("game %d, node %s", d.Tree, d.Path)
+	if d.Prop != "" {
+		where += ", " + d.Prop
+	}
+	return fmt.Sprintf("%s: %s: %s", where, d.Severity, d.Msg)
+}
+
+type propClass int
+
+const (
+	classNone propClass = iota
+	classRoot
+	classGameInfo
+	classMove
+	classSetup
+)
+
+type valueType int
+
+const (
+	typeNone valueType = iota
+	typeNumber
+	typeReal
+	typeDouble
+	typeColor
+	typeText
+	typeMove
+	typePoint
+	typeLabel
+	typePointPair
+	typeSize
+	typeFigure
+)
+
+type propInfo struct {
+	class propClass
+	typ   valueType
+	// list is true for properties that take a list of values, and
+	// elist for those that also allow an empty list
+	list, elist bool
+}
+
+// props describes the FF[4] properties that apply to Go. Properties
+// not listed are not checked.
+var props = map[string]propInfo{
+	// Move properties
+	"B":  {class: classMove, typ: typeMove},
+	"W":  {class: classMove, typ: typeMove},
+	"KO": {class: classMove, typ: typeNone},
+	"MN": {class: classMove, typ: typeNumber},
+	// Setup properties
+	"AB": {class: classSetup, typ: typePoint, list: true},
+	"AW": {class: classSetup, typ: typePoint, list: true},
+	"AE": {class: classSetup, typ: typePoint, list: true},
+	"PL": {class: classSetup, typ: typeColor},
+	// Node annotation properties
+	"C":  {typ: typeText},
+	"DM": {typ: typeDouble},
+	"GB": {typ: typeDouble},
+	"GW": {typ: typeDouble},
+	"HO": {typ: typeDouble},
+	"N":  {typ: typeText},
+	"UC": {typ: typeDouble},
+	"V":  {typ: typeReal},
+	// Move annotation properties
+	"BM": {class: classMove, typ: typeDouble},
+	"DO": {class: classMove, typ: typeNone},
+	"IT": {class: classMove, typ: typeNone},
+	"TE": {class: classMove, typ: typeDouble},
+	// Markup properties
+	"AR": {typ: typePointPair, list: true},
+	"CR": {typ: typePoint, list: true},
+	"DD": {typ: typePoint, list: true, elist: true},
+	"LB": {typ: typeLabel, list: true},
+	"LN": {typ: typePointPair, list: true},
+	"MA": {typ: typePoint, list: true},
+	"SL": {typ: typePoint, list: true},
+	"SQ": {typ: typePoint, list: true},
+	"TR": {typ: typePoint, list: true},
+	// Root properties
+	"AP": {class: classRoot, typ: typeText},
+	"CA": {class: classRoot, typ: typeText},
+	"FF": {class: classRoot, typ: typeNumber},
+	"GM": {class: classRoot, typ: typeNumber},
+	"ST": {class: classRoot, typ: typeNumber},
+	"SZ": {class: classRoot, typ: typeSize},
+	// Game info properties
+	"AN": {class: classGameInfo, typ: typeText},
+	"BR": {class: classGameInfo, typ: typeText},
+	"BT": {class: classGameInfo, typ: typeText},
+	"CP": {class: classGameInfo, typ: typeText},
+	"DT": {class: classGameInfo, typ: typeText},
+	"EV": {class: classGameInfo, typ: typeText},
+	"GN": {class: classGameInfo, typ: typeText},
+	"GC": {class: classGameInfo, typ: typeText},
+	"ON": {class: classGameInfo, typ: typeText},
+	"OT": {class: classGameInfo, typ: typeText},
+	"PB": {class: classGameInfo, typ: typeText},
+	"PC": {class: classGameInfo, typ: typeText},
+	"PW": {class: classGameInfo, typ: typeText},
+	"RE": {class: classGameInfo, typ: typeText},
+	"RO": {class: classGameInfo, typ: typeText},
+	"RU": {class: classGameInfo, typ: typeText},
+	"SO": {class: classGameInfo, typ: typeText},
+	"TM": {class: classGameInfo, typ: typeReal},
+	"US": {class: classGameInfo, typ: typeText},
+	"WR": {class: classGameInfo, typ: typeText},
+	"WT": {class: classGameInfo, typ: typeText},
+	"HA": {class: classGameInfo, typ: typeNumber},
+	"KM": {class: classGameInfo, typ: typeReal},
+	// Timing properties
+	"BL": {class: classMove, typ: typeReal},
+	"OB": {class: classMove, typ: typeNumber},
+	"OW": {class: classMove, typ: typeNumber},
+	"WL": {class: classMove, typ: typeReal},
+	// Miscellaneous properties
+	"FG": {typ: typeFigure},
+	"PM": {typ: typeNumber},
+	"VW": {typ: typePoint, list: true, elist: true},
+	// Go-specific properties
+	"TB": {typ: typePoint, list: true, elist: true},
+	"TW": {typ: typePoint, list: true, elist: true},
+}
+
+type validator struct {
+	diags         []*Diagnostic
+	tree          int
+	path          Path
+	width, height int
+	// info is the path of the node containing game info
+	// properties on the current path, if any
+	info *Path
+}
+
+func (v *validator) report(sev Severity, prop, format string, args ...interface{}) {
+	v.diags = append(v.diags, &Diagnostic{
+		Severity: sev,
+		Tree:     v.tree,
+		Path: Path{
+			Variations: append([]int(nil), v.path.Variations...),
+			Node:       v.path.Node,
+		},
+		Prop: prop,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks a collection against the FF[4] specification and
+// its Go-specific rules, returning any problems found in the order in
+// which they appear in the collection.
+func Validate(c *Collection) []*Diagnostic {
+	v := &validator{}
+	for i, t := range c.Trees {
+		v.tree = i
+		v.path = Path{}
+		v.info = nil
+		v.width, v.height = DefaultBoardSize, DefaultBoardSize
+		if len(t.Principal.Nodes) == 0 {
+			v.report(SeverityError, "", "empty game tree")
+			continue
+		}
+		root := &t.Principal.Nodes[0]
+		if w, h, err := root.BoardSize(); err != nil {
+			v.report(SeverityError, "SZ", "%v", err)
+		} else {
+			v.width, v.height = w, h
+		}
+		if val, ok := root.Value("GM"); ok && val != "1" {
+			v.report(SeverityWarning, "GM", "game type %s is not Go", val)
+		}
+		v.gameTree(t)
+	}
+	return v.diags
+}
+
+func (v *validator) gameTree(t *GameTree) {
+	info := v.info
+	for i := range t.Principal.Nodes {
+		v.path.Node = i
+		v.node(&t.Principal.Nodes[i], len(v.path.Variations) == 0 && i == 0)
+	}
+	branch := v.info
+	for i, c := range t.Children {
+		v.info = branch
+		v.path.Variations = append(v.path.Variations, i)
+		v.gameTree(c)
+		v.path.Variations = v.path.Variations[:len(v.path.Variations)-1]
+	}
+	v.info = info
+}
+
+func (v *validator) node(n *Node, root bool) {
+	seen := make(map[string]bool)
+	var move, setup string
+	var hasInfo bool
+	for _, p := range n.Props {
+		if seen[p.Prop] {
+			v.report(SeverityError, p.Prop, "duplicate property")
+		}
+		seen[p.Prop] = true
+		if !validIdent(p.Prop) {
+			v.report(SeverityError, p.Prop, "invalid property identifier")
+			continue
+		}
+		info, ok := props[p.Prop]
+		if !ok {
+			continue
+		}
+		switch info.class {
+		case classRoot:
+			if !root {
+				v.report(SeverityError, p.Prop, "root property outside of root node")
+			}
+		case classMove:
+			if move == "" {
+				move = p.Prop
+			}
+		case classSetup:
+			if setup == "" {
+				setup = p.Prop
+			}
+		case classGameInfo:
+			hasInfo = true
+		}
+		v.values(p, info)
+	}
+	if seen["B"] && seen["W"] {
+		v.report(SeverityError, "", "node contains both B and W")
+	}
+	if move != "" && setup != "" {
+		v.report(SeverityError, "", "node mixes move property %s and setup property %s", move, setup)
+	}
+	if seen["KO"] && !seen["B"] && !seen["W"] {
+		v.report(SeverityError, "KO", "KO without a move")
+	}
+	for _, p := range []string{"BM", "DO", "IT", "TE"} {
+		if seen[p] && !seen["B"] && !seen["W"] {
+			v.report(SeverityWarning, p, "move annotation without a move")
+		}
+	}
+	if seen["BM"] && seen["TE"] {
+		v.report(SeverityError, "", "node contains both BM and TE")
+	}
+	v.setupOverlap(n)
+	if hasInfo {
+		if v.info != nil {
+			v.report(SeverityError, "", "game info properties already given at node %s", *v.info)
+		} else {
+			v.info = &Path{
+				Variations: append([]int(nil), v.path.Variations...),
+				Node:       v.path.Node,
+			}
+		}
+	}
+	if val, ok := n.Value("HA"); ok {
+		if h, err := val.Number(); err == nil && (h == 1 || h < 0) {
+			v.report(SeverityWarning, "HA", "handicap of %d", h)
+		}
+	}
+}
+
+func validIdent(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !isUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// setupOverlap reports points that are set by more than one setup
+// property in the same node
+func (v *validator) setupOverlap(n *Node) {
+	set := make(map[PropValue]string)
+	for _, p := range n.Props {
+		if p.Prop != "AB" && p.Prop != "AW" && p.Prop != "AE" {
+			continue
+		}
+		for _, val := range p.Values {
+			x0, y0, x1, y1, err := val.PointRange()
+			if err != nil {
+				continue
+			}
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					pt := PointValue(x, y)
+					if prev, ok := set[pt]; ok {
+						v.report(SeverityError, p.Prop, "point %s already set by %s", pt, prev)
+					}
+					set[pt] = p.Prop
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) values(p Property, info propInfo) {
+	switch {
+	case len(p.Values) == 0:
+		v.report(SeverityError, p.Prop, "no values")
+		return
+	case len(p.Values) > 1 && !info.list:
+		v.report(SeverityError, p.Prop, "%d values for single-valued property", len(p.Values))
+	case info.list && len(p.Values) == 1 && p.Values[0] == "":
+		if !info.elist {
+			v.report(SeverityError, p.Prop, "empty list")
+		}
+		return
+	}
+	for _, val := range p.Values {
+		if msg := v.value(val, info.typ); msg != "" {
+			v.report(SeverityError, p.Prop, "%s: %q", msg, string(val))
+		}
+	}
+}
+
+// value checks a single value against its type and returns a
+// description of the problem, if any
+func (v *validator) value(val PropValue, typ valueType) string {
+	switch typ {
+	case typeNone:
+		if val != "" {
+			return "expected no value"
+		}
+	case typeNumber:
+		if _, err := val.Number(); err != nil {
+			return "expected a number"
+		}
+	case typeReal:
+		if _, err := val.Real(); err != nil {
+			return "expected a real number"
+		}
+	case typeDouble:
+		if val != "1" && val != "2" {
+			return "expected 1 or 2"
+		}
+	case typeColor:
+		if val != "B" && val != "W" {
+			return "expected B or W"
+		}
+	case typeText:
+	case typeMove:
+		if val == "" || (val == "tt" && v.width <= 19 && v.height <= 19) {
+			return ""
+		}
+		return v.point(val)
+	case typePoint:
+		a, b, ok := val.Compose()
+		if !ok {
+			return v.point(val)
+		}
+		if msg := v.point(a); msg != "" {
+			return msg
+		}
+		return v.point(b)
+	case typeLabel:
+		a, _, ok := val.Compose()
+		if !ok {
+			return "expected point:text"
+		}
+		return v.point(a)
+	case typePointPair:
+		a, b, ok := val.Compose()
+		if !ok {
+			return "expected point:point"
+		}
+		if msg := v.point(a); msg != "" {
+			return msg
+		}
+		if msg := v.point(b); msg != "" {
+			return msg
+		}
+		if a == b {
+			return "start and end points are the same"
+		}
+	case typeSize:
+	case typeFigure:
+		if val == "" {
+			return ""
+		}
+		a, _, ok := val.Compose()
+		if !ok {
+			return "expected number:text"
+		}
+		if _, err := a.Number(); err != nil {
+			return "expected number:text"
+		}
+	}
+	return ""
+}
+
+func (v *validator) point(val PropValue) string {
+	x, y, err := val.Point()
+	if err != nil || x < 0 {
+		return "expected a point"
+	}
+	if x >= v.width || y >= v.height {
+		return fmt.Sprintf("point off the %dx%d board", v.width, v.height)
+	}
+	return ""
+}
+
+// HasErrors returns true if any of diags has SeverityError
+func HasErrors(diags []*Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package sgf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	c, err := ParseSGF(strings.NewReader(basic))
+	if err != nil {
+		t.Fatal("parse:", err)
+	}
+	if diags := Validate(c); len(diags) != 0 {
+		t.Errorf("basic: %v", diags)
+	}
+
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"(;SZ[9];B[aa]B[bb])", []string{"game 0, node 1, B: error: duplicate property"}},
+		{"(;SZ[9];B[aa]W[bb])", []string{"game 0, node 1: error: node contains both B and W"}},
+		{"(;SZ[9];B[aa];SZ[19])", []string{"game 0, node 2, SZ: error: root property outside of root node"}},
+		{"(;SZ[9];B[jj])", []string{`game 0, node 1, B: error: point off the 9x9 board: "jj"`}},
+		{"(;SZ[19:5];B[af])", []string{`game 0, node 1, B: error: point off the 19x5 board: "af"`}},
+		{"(;SZ[9];B[aa]AW[bb])", []string{"game 0, node 1: error: node mixes move property B and setup property AW"}},
+		{"(;SZ[9];AB[aa:bb]AW[ba])", []string{`game 0, node 1, AW: error: point ba already set by AB`}},
+		{"(;SZ[9](;W[tt]PB[x])(;B[cc]PW[y];W[dd]KM[0.5]))", []string{
+			"game 0, node 1.1: error: game info properties already given at node 1.0",
+		}},
+		{"(;SZ[9]KM[lots];B[aa]TE[3])", []string{
+			`game 0, node 0, KM: error: expected a real number: "lots"`,
+			`game 0, node 1, TE: error: expected 1 or 2: "3"`,
+		}},
+		{"(;GM[2])", []string{"game 0, node 0, GM: warning: game type 2 is not Go"}},
+	}
+	for _, tc := range cases {
+		c, err := ParseSGF(strings.NewReader(tc.in))
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.in, err)
+		}
+		var got []string
+		for _, d := range Validate(c) {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tc.in, got, tc.want)
+		}
+	}
+}
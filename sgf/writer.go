@@ -0,0 +1,67 @@
+package sgf
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, `]`, `\]`)
+
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) str(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.WriteString(s)
+}
+
+func (w *writer) tree(t *GameTree, root *Node) {
+	w.str("(")
+	for i := range t.Principal.Nodes {
+		if i == 0 && root != nil {
+			w.node(root)
+		} else {
+			w.node(&t.Principal.Nodes[i])
+		}
+	}
+	for _, c := range t.Children {
+		w.str("\n")
+		w.tree(c, nil)
+	}
+	w.str(")")
+}
+
+func (w *writer) node(n *Node) {
+	w.str(";")
+	for _, p := range n.Props {
+		w.str(p.Prop)
+		for _, v := range p.Values {
+			w.str("[")
+			w.str(valueEscaper.Replace(string(v)))
+			w.str("]")
+		}
+	}
+}
+
+// WriteSGF serializes a collection in SGF text form. Output is always
+// UTF-8; the CA property of each game tree is rewritten to say so.
+func WriteSGF(out io.Writer, c *Collection) error {
+	w := &writer{w: bufio.NewWriter(out)}
+	for _, t := range c.Trees {
+		var root *Node
+		if len(t.Principal.Nodes) > 0 {
+			root = outputRoot(t)
+		}
+		w.tree(t, root)
+		w.str("\n")
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
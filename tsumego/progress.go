@@ -0,0 +1,115 @@
+package tsumego
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// Progress records a player's results on a set of problems, keyed by
+// an identifier chosen by the caller
+type Progress struct {
+	Problems map[string]*Result `json:"problems"`
+}
+
+// Result is the record of attempts at one problem
+type Result struct {
+	// Attempts counts the finished attempts
+	Attempts int `json:"attempts"`
+	Solved   int `json:"solved"`
+	// Last is the State of the most recent finished attempt
+	Last string `json:"last,omitempty"`
+}
+
+// ProblemID returns an identifier for a problem that does not depend
+// on its place in a collection: its name, from the GN property, if it
+// has one, or else a hash of its size and setup
+func ProblemID(t *sgf.GameTree) string {
+	root := &t.Principal.Nodes[0]
+	if v, ok := root.Value("GN"); ok && v != "" {
+		return string(v)
+	}
+	h := fnv.New64a()
+	for _, prop := range []string{"SZ", "AB", "AW", "AE", "PL"} {
+		var vals []string
+		if p := root.Get(prop); p != nil {
+			for _, v := range p.Values {
+				vals = append(vals, string(v))
+			}
+		}
+		sort.Strings(vals)
+		fmt.Fprintf(h, "%s%q", prop, vals)
+	}
+	return fmt.Sprintf("setup-%016x", h.Sum64())
+}
+
+// NewProgress returns an empty record of progress
+func NewProgress() *Progress {
+	return &Progress{Problems: make(map[string]*Result)}
+}
+
+// LoadProgress reads progress written by Save. A missing file is
+// treated as empty.
+func LoadProgress(path string) (*Progress, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewProgress(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := NewProgress()
+	if err := json.NewDecoder(f).Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if p.Problems == nil {
+		p.Problems = make(map[string]*Result)
+	}
+	return p, nil
+}
+
+// Record adds the outcome of a finished attempt
+func (p *Progress) Record(id string, s State) {
+	r := p.Problems[id]
+	if r == nil {
+		r = &Result{}
+		p.Problems[id] = r
+	}
+	r.Attempts++
+	if s == Solved {
+		r.Solved++
+	}
+	r.Last = s.String()
+}
+
+// Get returns the results for a problem, which are zero if it has not
+// been attempted
+func (p *Progress) Get(id string) Result {
+	if r := p.Problems[id]; r != nil {
+		return *r
+	}
+	return Result{}
+}
+
+// Save writes the progress to a file, replacing it atomically
+func (p *Progress) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
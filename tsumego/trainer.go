@@ -0,0 +1,162 @@
+package tsumego
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+// State is the status of an attempt at a problem
+type State int
+
+const (
+	// Playing means the attempt is still in progress
+	Playing State = iota
+	// Solved means the player reached a line marked RIGHT
+	Solved
+	// Failed means the player left the problem's correct lines
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Solved:
+		return "solved"
+	case Failed:
+		return "failed"
+	default:
+		return "playing"
+	}
+}
+
+// ErrFinished is returned when playing in an attempt that has ended
+var ErrFinished = errors.New("tsumego: the problem is finished")
+
+// An Attempt follows a player through a problem recorded as an SGF
+// game tree, in the style of goproblems.com: the nodes before the
+// first move set up the position, each variation is a line of play
+// starting with the player's move, and a line is correct if its last
+// node has a comment containing "RIGHT".
+type Attempt struct {
+	// Game holds the current position
+	Game *game.Game
+	// Color is the player solving the problem
+	Color game.Color
+	State State
+	cur   *sgf.Cursor
+}
+
+// NewAttempt sets up a problem from its game tree
+func NewAttempt(t *sgf.GameTree) (*Attempt, error) {
+	if len(t.Principal.Nodes) == 0 {
+		return nil, errors.New("tsumego: empty game tree")
+	}
+	w, h, err := t.Principal.Nodes[0].BoardSize()
+	if err != nil {
+		return nil, err
+	}
+	a := &Attempt{Game: game.NewRect(w, h), cur: sgf.NewCursor(t)}
+	for {
+		if err := a.Game.PlayNode(a.cur.Node()); err != nil {
+			return nil, err
+		}
+		vars := a.cur.Variations()
+		if len(vars) == 0 {
+			return nil, errors.New("tsumego: problem has no moves")
+		}
+		if c, _, _, ok := nodeMove(vars[0], w, h); ok {
+			a.Color = c
+			break
+		}
+		a.cur.Next()
+	}
+	return a, nil
+}
+
+// nodeMove returns the move made in a node, with -1,-1 for a pass
+func nodeMove(n *sgf.Node, width, height int) (c game.Color, x, y int, ok bool) {
+	v, ok := n.Value("B")
+	c = game.Black
+	if !ok {
+		if v, ok = n.Value("W"); !ok {
+			return c, 0, 0, false
+		}
+		c = game.White
+	}
+	x, y, err := v.Point()
+	if err != nil || x == 19 && y == 19 && width <= 19 && height <= 19 {
+		return c, -1, -1, true
+	}
+	return c, x, y, true
+}
+
+// Play makes the player's move at (x,y), or passes if both are -1. If
+// the move is one of the problem's lines, the problem's answer is
+// played in turn and returned; Play returns a nil Move if there is
+// none, or if the move was not anticipated by the problem, which fails
+// the attempt. An illegal move returns an error and leaves the attempt
+// unchanged.
+func (a *Attempt) Play(x, y int) (*Move, error) {
+	if a.State != Playing {
+		return nil, ErrFinished
+	}
+	w, h := a.Game.Width, a.Game.Height
+	for i, n := range a.cur.Variations() {
+		c, nx, ny, ok := nodeMove(n, w, h)
+		if !ok || c != a.Color || nx != x || ny != y {
+			continue
+		}
+		if err := a.advance(i); err != nil {
+			return nil, err
+		}
+		return a.reply()
+	}
+	if err := a.Game.Move(x, y); err != nil {
+		return nil, err
+	}
+	a.State = Failed
+	return nil, nil
+}
+
+// reply plays the problem's answer to the player's move, if it has
+// one
+func (a *Attempt) reply() (*Move, error) {
+	vars := a.cur.Variations()
+	if len(vars) == 0 {
+		a.finish()
+		return nil, nil
+	}
+	c, x, y, ok := nodeMove(vars[0], a.Game.Width, a.Game.Height)
+	if !ok || c == a.Color {
+		return nil, nil
+	}
+	if err := a.advance(0); err != nil {
+		return nil, err
+	}
+	if len(a.cur.Variations()) == 0 {
+		a.finish()
+	}
+	return &Move{X: x, Y: y, Color: c}, nil
+}
+
+func (a *Attempt) advance(i int) error {
+	if err := a.cur.SelectVariation(i); err != nil {
+		return err
+	}
+	if err := a.Game.PlayNode(a.cur.Node()); err != nil {
+		return fmt.Errorf("tsumego: bad problem: %v", err)
+	}
+	return nil
+}
+
+// finish judges a line that has reached a leaf
+func (a *Attempt) finish() {
+	if c, _ := a.cur.Node().Value("C"); strings.Contains(string(c), "RIGHT") {
+		a.State = Solved
+	} else {
+		a.State = Failed
+	}
+}
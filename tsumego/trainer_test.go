@@ -0,0 +1,140 @@
+package tsumego
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+func newAttempt(t *testing.T) *Attempt {
+	tree, _ := loadProblem(t)
+	a, err := NewAttempt(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAttempt(t *testing.T) {
+	a := newAttempt(t)
+	if a.Color != game.Black || a.Game.ToPlay() != game.Black {
+		t.Fatalf("color=%v to play=%v", a.Color, a.Game.ToPlay())
+	}
+	if c, ok := a.Game.At(0, 1); !ok || c != game.Black {
+		t.Fatal("setup was not played")
+	}
+
+	// B[ah] W[af] B[ae] W[bf] B[ag] W[bf] B[ce] W[af] B[bg] RIGHT
+	line := []struct {
+		x, y   int
+		rx, ry int
+	}{
+		{0, 7, 0, 5},
+		{0, 4, 1, 5},
+		{0, 6, 1, 5},
+		{2, 4, 0, 5},
+	}
+	for _, m := range line {
+		r, err := a.Play(m.x, m.y)
+		if err != nil {
+			t.Fatalf("Play(%d,%d): %v", m.x, m.y, err)
+		}
+		if r == nil || r.X != m.rx || r.Y != m.ry || r.Color != game.White {
+			t.Fatalf("Play(%d,%d): reply %+v", m.x, m.y, r)
+		}
+		if a.State != Playing {
+			t.Fatalf("Play(%d,%d): %v", m.x, m.y, a.State)
+		}
+	}
+	if r, err := a.Play(1, 6); err != nil || r != nil {
+		t.Fatalf("Play(1,6) = %v, %v", r, err)
+	}
+	if a.State != Solved {
+		t.Errorf("state %v, want solved", a.State)
+	}
+	if _, err := a.Play(8, 8); err != ErrFinished {
+		t.Errorf("Play after the end: %v", err)
+	}
+}
+
+func TestAttemptFails(t *testing.T) {
+	// A move the problem does not know about
+	a := newAttempt(t)
+	if _, err := a.Play(8, 8); err != nil {
+		t.Fatal(err)
+	}
+	if a.State != Failed {
+		t.Errorf("state %v after an unknown move", a.State)
+	}
+	if c, ok := a.Game.At(8, 8); !ok || c != game.Black {
+		t.Error("unknown move was not played")
+	}
+
+	// A line in the tree that is not marked RIGHT
+	a = newAttempt(t)
+	if _, err := a.Play(0, 4); err != nil {
+		t.Fatal(err)
+	}
+	if a.State != Failed {
+		t.Errorf("state %v after B[ae] W[ag]", a.State)
+	}
+
+	// An illegal move changes nothing
+	a = newAttempt(t)
+	if _, err := a.Play(0, 1); err == nil {
+		t.Error("move on a stone accepted")
+	}
+	if a.State != Playing {
+		t.Errorf("state %v after an illegal move", a.State)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	p, err := LoadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Record("0", Failed)
+	p.Record("0", Solved)
+	p.Record("3", Failed)
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = LoadProgress(path); err != nil {
+		t.Fatal(err)
+	}
+	if r := p.Get("0"); r != (Result{Attempts: 2, Solved: 1, Last: "solved"}) {
+		t.Errorf("0: %+v", r)
+	}
+	if r := p.Get("3"); r != (Result{Attempts: 1, Last: "failed"}) {
+		t.Errorf("3: %+v", r)
+	}
+	if r := p.Get("1"); r != (Result{}) {
+		t.Errorf("1: %+v", r)
+	}
+}
+
+func TestProblemID(t *testing.T) {
+	c, err := sgf.ParseSGF(strings.NewReader(`(;GN[Corner]SZ[9]AB[aa][bb])
+		(;SZ[9]AB[aa][bb]AW[cc]C[first])
+		(;SZ[9]AW[cc]AB[bb][aa]C[second])
+		(;SZ[9]AB[aa][bb]AW[cd])`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, tree := range c.Trees {
+		ids = append(ids, ProblemID(tree))
+	}
+	if ids[0] != "Corner" {
+		t.Errorf("named problem: %q", ids[0])
+	}
+	// The setup, but not its order or comments, identifies a problem
+	if ids[1] != ids[2] || ids[1] == ids[3] || ids[1] == ids[0] {
+		t.Errorf("ids: %q", ids)
+	}
+}
@@ -0,0 +1,540 @@
+// Package tsumego solves life-and-death problems. A problem is a
+// position, a target group and a region of the board in which moves
+// may be played; the solver searches for a way for one side to kill
+// the group, or for the other to make it live.
+//
+// The attacker is assumed to control everything outside the region:
+// empty points there are filled with attacking stones before the
+// search, and stones outside the region can never be captured. The
+// region should therefore include the target group and every point
+// the defender might use.
+package tsumego
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"nelhage.com/minigo/bit"
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+// Goal is what a player is trying to achieve
+type Goal int
+
+const (
+	// Live means keeping the target group on the board
+	Live Goal = iota
+	// Kill means capturing the target group
+	Kill
+)
+
+func (g Goal) String() string {
+	if g == Kill {
+		return "kill"
+	}
+	return "live"
+}
+
+// DefaultMaxNodes bounds the search if Problem.MaxNodes is zero
+const DefaultMaxNodes = 2000000
+
+var (
+	// ErrTooHard is returned if a problem cannot be solved within
+	// the node budget
+	ErrTooHard = errors.New("tsumego: search limit exceeded")
+)
+
+// Problem describes a life-and-death problem
+type Problem struct {
+	// Game holds the position to solve, and the player to play
+	Game *game.Game
+	// Region lists the points where moves may be played. Empty
+	// points outside it are treated as the attacker's.
+	Region []sgf.Point
+	// Target is a stone of the group whose life is in question
+	Target sgf.Point
+	// Color is the player whose goal is being solved for. If Goal
+	// is Live, the target must be Color's stone; if Kill, the
+	// opponent's.
+	Color game.Color
+	Goal  Goal
+	// MaxNodes bounds the number of positions searched
+	MaxNodes int
+}
+
+// Move is a node in a solution tree
+type Move struct {
+	// X and Y are the point played, or -1,-1 for a pass
+	X, Y  int
+	Color game.Color
+	// Replies are the moves considered in answer to this one
+	Replies []*Move
+}
+
+// Solution is the result of solving a problem
+type Solution struct {
+	// Success is true if Problem.Color achieves the goal against any
+	// defense
+	Success bool
+	// Moves is the solution tree. When the problem's player is to
+	// play, it holds one winning move; otherwise it holds every
+	// reply by the opponent, each followed by an answer. Branches
+	// end when the outcome is settled. Moves is empty if Success is
+	// false.
+	Moves []*Move
+	// Nodes is the number of positions searched
+	Nodes int
+}
+
+type solver struct {
+	p *Problem
+	// g is the part of the problem's board being searched, which is
+	// offset by (x0, y0) from the original
+	g      *game.Game
+	x0, y0 int
+	region []int
+	// outside marks the points of g outside the region, and
+	// outsideStones counts the stones there, which cannot be
+	// captured
+	outside       *bit.Vector
+	outsideStones int
+	target        int
+	// defender is the color of the target group
+	defender game.Color
+	max      int
+	nodes    int
+	// path holds the positions on the current line, to forbid
+	// repeating one, with their depth along it, counting the
+	// problem's position as 1. repeated is the least depth of a
+	// position a move was refused for repeating, since the search
+	// of the current position began.
+	path     map[uint64]int
+	repeated int
+	// results caches the outcome of each position searched, unless
+	// it depended on the line that reached it
+	results map[uint64]bool
+	// shown holds the positions already in the solution tree
+	shown map[uint64]bool
+}
+
+// Solve searches for a solution to a problem. The problem's game is
+// not modified.
+func Solve(p *Problem) (*Solution, error) {
+	g := p.Game
+	tx, ty := p.Target.X, p.Target.Y
+	if tx < 0 || tx >= g.Width || ty < 0 || ty >= g.Height {
+		return nil, fmt.Errorf("tsumego: target %v is off the board", p.Target)
+	}
+	defender, ok := g.At(tx, ty)
+	if !ok {
+		return nil, fmt.Errorf("tsumego: no stone at target %v", p.Target)
+	}
+	if (p.Goal == Live) != (defender == p.Color) {
+		return nil, fmt.Errorf("tsumego: cannot %s the target as %s", p.Goal, colorName(p.Color))
+	}
+	if len(p.Region) == 0 {
+		return nil, errors.New("tsumego: empty region")
+	}
+	for _, pt := range p.Region {
+		if pt.X < 0 || pt.X >= g.Width || pt.Y < 0 || pt.Y >= g.Height {
+			return nil, fmt.Errorf("tsumego: region point %v is off the board", pt)
+		}
+	}
+	s := &solver{
+		p:        p,
+		defender: defender,
+		max:      p.MaxNodes,
+		path:     make(map[uint64]int),
+		repeated: math.MaxInt,
+		results:  make(map[uint64]bool),
+	}
+	if s.max == 0 {
+		s.max = DefaultMaxNodes
+	}
+	s.g, s.x0, s.y0 = enclose(g, p.Region, !defender)
+	s.outside = bit.NewVector(s.g.Width * s.g.Height).Not()
+	for _, pt := range p.Region {
+		s.region = append(s.region, s.index(pt.X, pt.Y))
+		s.outside.Clear(s.index(pt.X, pt.Y))
+	}
+	s.outsideStones = s.stonesOutside(s.g.Position())
+	if tx < s.x0 || tx >= s.x0+s.g.Width || ty < s.y0 || ty >= s.y0+s.g.Height {
+		return nil, fmt.Errorf("tsumego: target %v is not next to the region", p.Target)
+	}
+	s.target = s.index(tx, ty)
+
+	s.path[s.positionKey()] = 1
+	success, err := s.search(0)
+	if err != nil {
+		return nil, err
+	}
+	sol := &Solution{Success: success, Nodes: s.nodes}
+	if success {
+		s.shown = make(map[uint64]bool)
+		sol.Moves = s.tree(0)
+	}
+	return sol, nil
+}
+
+// enclose returns a game holding the part of g around a region: the
+// region's bounding box, plus a margin of two points where the board
+// extends beyond it. Empty points outside the region are filled with
+// the attacker's stones, except on the outer edge of the margin, where
+// they give the attacker's wall liberties that neither side can fill.
+// It also returns the offset of the new board within g.
+func enclose(g *game.Game, region []sgf.Point, attacker game.Color) (*game.Game, int, int) {
+	x0, y0, x1, y1 := g.Width, g.Height, -1, -1
+	for _, pt := range region {
+		x0, y0 = min(x0, pt.X), min(y0, pt.Y)
+		x1, y1 = max(x1, pt.X), max(y1, pt.Y)
+	}
+	x0, y0 = max(x0-2, 0), max(y0-2, 0)
+	x1, y1 = min(x1+2, g.Width-1), min(y1+2, g.Height-1)
+
+	w, h := x1-x0+1, y1-y0+1
+	inRegion := bit.NewVector(w * h)
+	for _, pt := range region {
+		inRegion.Set((pt.Y-y0)*w + pt.X - x0)
+	}
+	// rim reports whether a point is on the edge of the new board
+	// but not of g
+	rim := func(x, y int) bool {
+		return x == 0 && x0 > 0 || x == w-1 && x1 < g.Width-1 ||
+			y == 0 && y0 > 0 || y == h-1 && y1 < g.Height-1
+	}
+	p := &game.Position{
+		Width:  w,
+		Height: h,
+		Black:  bit.NewVector(w * h),
+		White:  bit.NewVector(w * h),
+		ToPlay: g.ToPlay(),
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			c, ok := g.At(x+x0, y+y0)
+			if !ok && !inRegion.At(i) && !rim(x, y) {
+				c, ok = attacker, true
+			}
+			switch {
+			case !ok:
+			case c == game.Black:
+				p.Black.Set(i)
+			default:
+				p.White.Set(i)
+			}
+		}
+	}
+	return game.FromPosition(p), x0, y0
+}
+
+// index returns the index in s.g of a point on the problem's board
+func (s *solver) index(x, y int) int {
+	return (y-s.y0)*s.g.Width + x - s.x0
+}
+
+func colorName(c game.Color) string {
+	if c == game.White {
+		return "white"
+	}
+	return "black"
+}
+
+func (s *solver) positionKey() uint64 {
+	return hashPosition(s.g.Position())
+}
+
+func hashPosition(p *game.Position) uint64 {
+	h := fnv.New64a()
+	h.Write(p.Black.Bytes())
+	h.Write(p.White.Bytes())
+	return h.Sum64()
+}
+
+// stateKey identifies a search state: the position, the player to
+// play, the point a ko forbids retaking, if any, and whether the last
+// move was a pass
+func (s *solver) stateKey(passes int) uint64 {
+	k := s.positionKey()
+	if s.g.ToPlay() == game.White {
+		k ^= 0x9e3779b97f4a7c15
+	}
+	if x, y, ok := s.g.KoPoint(); ok {
+		k ^= uint64(y*s.g.Width+x+1) * 0xff51afd7ed558ccd
+	}
+	if passes > 0 {
+		k ^= 0xc2b2ae3d27d4eb4f
+	}
+	return k
+}
+
+// outcome evaluates a position statically, returning whether the
+// problem's player has succeeded and whether the result is settled
+func (s *solver) outcome() (success, settled bool) {
+	x, y := s.target%s.g.Width, s.target/s.g.Width
+	if c, ok := s.g.At(x, y); !ok || c != s.defender {
+		return s.p.Goal == Kill, true
+	}
+	if s.alive(s.g.Chain(x, y)) {
+		return s.p.Goal == Live, true
+	}
+	return false, false
+}
+
+// alive returns true if a chain has two eyes that the opponent cannot
+// fill: empty points whose neighbors all belong to the chain. Neither
+// can be played in while the other is open, so the chain can never be
+// captured.
+func (s *solver) alive(chain *bit.Vector) bool {
+	libs := s.g.Liberties(chain)
+	eyes := 0
+	w, h := s.g.Width, s.g.Height
+	for i := 0; i < libs.Len() && eyes < 2; i++ {
+		if !libs.At(i) {
+			continue
+		}
+		x, y := i%w, i/w
+		eye := true
+		for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			nx, ny := x+d[0], y+d[1]
+			if nx >= 0 && nx < w && ny >= 0 && ny < h && !chain.At(ny*w+nx) {
+				eye = false
+				break
+			}
+		}
+		if eye {
+			eyes++
+		}
+	}
+	return eyes >= 2
+}
+
+// play makes a move, returning false if it is illegal, captures
+// stones outside the region or repeats a position on the current line
+func (s *solver) play(idx int) bool {
+	x, y := -1, -1
+	if idx >= 0 {
+		x, y = idx%s.g.Width, idx/s.g.Width
+	}
+	if err := s.g.Move(x, y); err != nil {
+		return false
+	}
+	if idx < 0 {
+		return true
+	}
+	p := s.g.Position()
+	if s.stonesOutside(p) != s.outsideStones {
+		s.g.Undo()
+		return false
+	}
+	k := hashPosition(p)
+	if d := s.path[k]; d > 0 {
+		if d < s.repeated {
+			s.repeated = d
+		}
+		s.g.Undo()
+		return false
+	}
+	s.path[k] = len(s.path) + 1
+	return true
+}
+
+func (s *solver) stonesOutside(p *game.Position) int {
+	return p.Black.Copy().Or(p.White).And(s.outside).Popcount()
+}
+
+func (s *solver) undo(idx int) {
+	if idx >= 0 {
+		delete(s.path, s.positionKey())
+	}
+	s.g.Undo()
+}
+
+// moves lists the candidate moves: the region's empty points, then a
+// pass. The target's liberties come first, since they are most often
+// urgent, and the player to play never fills one of its own eyes.
+func (s *solver) moves() []int {
+	w := s.g.Width
+	libs := s.g.Liberties(s.g.Chain(s.target%w, s.target/w))
+	toPlay := s.g.ToPlay()
+	var urgent, rest []int
+	for _, i := range s.region {
+		if _, ok := s.g.At(i%w, i/w); ok || s.isEye(i, toPlay) {
+			continue
+		}
+		if libs.At(i) {
+			urgent = append(urgent, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	return append(append(urgent, rest...), -1)
+}
+
+// isEye returns true if an empty point is an eye of color c: its
+// neighbors are all c's stones, and enough of its diagonal neighbors
+// are too that the opponent cannot make it false
+func (s *solver) isEye(i int, c game.Color) bool {
+	w, h := s.g.Width, s.g.Height
+	x, y := i%w, i/w
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		nx, ny := x+d[0], y+d[1]
+		if nx < 0 || nx >= w || ny < 0 || ny >= h {
+			continue
+		}
+		if nc, ok := s.g.At(nx, ny); !ok || nc != c {
+			return false
+		}
+	}
+	bad, edge := 0, false
+	for _, d := range [][2]int{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		nx, ny := x+d[0], y+d[1]
+		if nx < 0 || nx >= w || ny < 0 || ny >= h {
+			edge = true
+			continue
+		}
+		if nc, ok := s.g.At(nx, ny); ok && nc != c {
+			bad++
+		}
+	}
+	if edge {
+		return bad == 0
+	}
+	return bad <= 1
+}
+
+// search returns whether the problem's player succeeds from the
+// current position. passes is the number of consecutive passes that
+// led to it; two end the search, leaving the target on the board.
+//
+// A result is not cached if a move was refused during its search for
+// repeating a position earlier on the current line than this one,
+// since it may not hold when the position is reached along another
+// line.
+func (s *solver) search(passes int) (bool, error) {
+	if success, settled := s.outcome(); settled {
+		return success, nil
+	}
+	if passes >= 2 {
+		return s.p.Goal == Live, nil
+	}
+	key := s.stateKey(passes)
+	if r, ok := s.results[key]; ok {
+		return r, nil
+	}
+	s.nodes++
+	if s.nodes > s.max {
+		return false, ErrTooHard
+	}
+
+	mine := s.g.ToPlay() == s.p.Color
+	result := !mine
+	outer := s.repeated
+	s.repeated = math.MaxInt
+	for _, m := range s.moves() {
+		if !s.play(m) {
+			continue
+		}
+		next := 0
+		if m < 0 {
+			next = passes + 1
+		}
+		r, err := s.search(next)
+		s.undo(m)
+		if err != nil {
+			return false, err
+		}
+		if r == mine {
+			result = r
+			break
+		}
+	}
+	if s.repeated >= len(s.path) {
+		s.results[key] = result
+	}
+	s.repeated = min(s.repeated, outer)
+	return result, nil
+}
+
+// tree builds the solution tree for a position already known to be a
+// success. Positions reached by transposition are only expanded the
+// first time, and the opponent's passes are only shown when it has
+// no other move.
+func (s *solver) tree(passes int) []*Move {
+	if _, settled := s.outcome(); settled || passes >= 2 {
+		return nil
+	}
+	key := s.stateKey(passes)
+	if s.shown[key] {
+		return nil
+	}
+	s.shown[key] = true
+	mine := s.g.ToPlay() == s.p.Color
+	var out []*Move
+	for _, m := range s.moves() {
+		if m < 0 && !mine && len(out) > 0 {
+			break
+		}
+		if !s.play(m) {
+			continue
+		}
+		next := 0
+		if m < 0 {
+			next = passes + 1
+		}
+		// Every position below a success was searched, or cut off
+		// by a move that was, so the cache holds the answers
+		r, _ := s.search(next)
+		if r {
+			mv := &Move{X: -1, Y: -1, Color: !s.g.ToPlay()}
+			if m >= 0 {
+				mv.X, mv.Y = m%s.g.Width+s.x0, m/s.g.Width+s.y0
+			}
+			mv.Replies = s.tree(next)
+			out = append(out, mv)
+		}
+		s.undo(m)
+		if r && mine {
+			break
+		}
+	}
+	return out
+}
+
+// GameTree converts the solution to an SGF game tree that starts with
+// root, for example the problem's setup node. The last move of each
+// successful line is commented "RIGHT".
+func (sol *Solution) GameTree(root sgf.Node) *sgf.GameTree {
+	t := &sgf.GameTree{}
+	t.Principal.Nodes = []sgf.Node{root}
+	addMoves(t, sol.Moves)
+	return t
+}
+
+func addMoves(t *sgf.GameTree, moves []*Move) {
+	if len(moves) == 1 {
+		appendMove(t, moves[0])
+		return
+	}
+	for _, m := range moves {
+		child := &sgf.GameTree{}
+		appendMove(child, m)
+		t.Children = append(t.Children, child)
+	}
+}
+
+func appendMove(t *sgf.GameTree, m *Move) {
+	prop := "B"
+	if m.Color == game.White {
+		prop = "W"
+	}
+	n := sgf.Node{Props: []sgf.Property{{Prop: prop, Values: []sgf.PropValue{sgf.PointValue(m.X, m.Y)}}}}
+	if len(m.Replies) == 0 {
+		n.Props = append(n.Props, sgf.Property{Prop: "C", Values: []sgf.PropValue{"RIGHT"}})
+	}
+	t.Principal.Nodes = append(t.Principal.Nodes, n)
+	addMoves(t, m.Replies)
+}
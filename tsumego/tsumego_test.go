@@ -0,0 +1,193 @@
+package tsumego
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+func loadProblem(t *testing.T) (*sgf.GameTree, *game.Game) {
+	f, err := os.Open("testdata/goproblems.sgf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := sgf.ParseSGF(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := c.Trees[0]
+	g := game.New(19)
+	for i := range tree.Principal.Nodes {
+		if err := g.PlayNode(&tree.Principal.Nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree, g
+}
+
+// region is the left edge of the board inside white's wall
+func region() []sgf.Point {
+	var out []sgf.Point
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 3; x++ {
+			out = append(out, sgf.Point{X: x, Y: y})
+		}
+	}
+	return out
+}
+
+func problem(g *game.Game) *Problem {
+	return &Problem{
+		Game:   g,
+		Region: region(),
+		// ab, the corner group
+		Target: sgf.Point{X: 0, Y: 1},
+		Color:  game.Black,
+		Goal:   Live,
+	}
+}
+
+func mustSolve(t *testing.T, p *Problem) *Solution {
+	t.Helper()
+	sol, err := Solve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sol
+}
+
+func TestSolve(t *testing.T) {
+	_, g := loadProblem(t)
+	sol := mustSolve(t, problem(g))
+	if !sol.Success {
+		t.Fatal("black cannot live")
+	}
+	if len(sol.Moves) != 1 {
+		t.Fatalf("%d first moves", len(sol.Moves))
+	}
+	if m := sol.Moves[0]; m.X != 0 || m.Y != 7 || m.Color != game.Black {
+		t.Errorf("first move: %+v, want B[ah]", m)
+	}
+	if len(sol.Moves[0].Replies) == 0 {
+		t.Error("no replies to B[ah]")
+	}
+
+	// The killing side succeeds if black plays elsewhere first
+	for _, first := range []string{"af", "ae", "ag"} {
+		_, g := loadProblem(t)
+		x, y, _ := sgf.PropValue(first).Point()
+		if err := g.Move(x, y); err != nil {
+			t.Fatal(err)
+		}
+		p := problem(g)
+		p.Color, p.Goal = game.White, Kill
+		if sol := mustSolve(t, p); !sol.Success {
+			t.Errorf("B[%s]: white cannot kill", first)
+		}
+	}
+}
+
+// TestRightVariation checks that every black move in the variation
+// marked RIGHT keeps black alive, and that each white move does not
+// let white kill
+func TestRightVariation(t *testing.T) {
+	tree, g := loadProblem(t)
+	// Find the RIGHT variation's path by walking each branch
+	var line []*sgf.Node
+	var walk func(t *sgf.GameTree, prefix []*sgf.Node) bool
+	walk = func(t *sgf.GameTree, prefix []*sgf.Node) bool {
+		for i := range t.Principal.Nodes {
+			prefix = append(prefix, &t.Principal.Nodes[i])
+		}
+		if v, _ := prefix[len(prefix)-1].Value("C"); len(v) >= 5 && v[:5] == "RIGHT" {
+			line = prefix
+			return true
+		}
+		for _, ch := range t.Children {
+			if walk(ch, prefix[:len(prefix):len(prefix)]) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ch := range tree.Children {
+		walk(ch, nil)
+	}
+	if len(line) == 0 {
+		t.Fatal("no RIGHT variation")
+	}
+
+	for _, n := range line {
+		if err := g.PlayNode(n); err != nil {
+			t.Fatal(err)
+		}
+		p := problem(g)
+		if g.ToPlay() == game.White {
+			p.Color, p.Goal = game.White, Kill
+			if sol := mustSolve(t, p); sol.Success {
+				t.Errorf("after %v white can kill", n.Props)
+			}
+		} else if sol := mustSolve(t, p); !sol.Success {
+			t.Errorf("after %v black cannot live", n.Props)
+		}
+	}
+}
+
+func TestProblemErrors(t *testing.T) {
+	_, g := loadProblem(t)
+	p := problem(g)
+	p.Goal = Kill
+	if _, err := Solve(p); err == nil {
+		t.Error("black asked to kill its own group")
+	}
+	p = problem(g)
+	p.Target = sgf.Point{X: 0, Y: 0}
+	if _, err := Solve(p); err == nil {
+		t.Error("empty target accepted")
+	}
+	p = problem(g)
+	p.MaxNodes = 10
+	if _, err := Solve(p); err != ErrTooHard {
+		t.Errorf("err=%v, want ErrTooHard", err)
+	}
+}
+
+func TestGameTree(t *testing.T) {
+	_, g := loadProblem(t)
+	sol := mustSolve(t, problem(g))
+	tree := sol.GameTree(sgf.Node{})
+	if _, err := game.FromSGF(tree); err != nil {
+		t.Fatal("FromSGF:", err)
+	}
+	if len(tree.Principal.Nodes) < 2 {
+		t.Fatalf("tree: %+v", tree)
+	}
+	if v, _ := tree.Principal.Nodes[1].Value("B"); v != "ah" {
+		t.Errorf("first move %q", v)
+	}
+}
+
+func TestStateKeyKo(t *testing.T) {
+	c, err := sgf.ParseSGF(strings.NewReader(
+		"(;SZ[5]AB[ba][ab][bc][cb]AW[ca][db][cc]PL[W];W[bb])"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ko, err := game.FromSGF(c.Trees[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := ko.KoPoint(); !ok {
+		t.Fatal("no ko")
+	}
+	free := game.FromPosition(ko.Position())
+	a := (&solver{g: ko}).stateKey(0)
+	b := (&solver{g: free}).stateKey(0)
+	if a == b {
+		t.Error("a position with a ko ban has the key of one without")
+	}
+}
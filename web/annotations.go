@@ -0,0 +1,93 @@
+package web
+
+import (
+	"nelhage.com/minigo/bit"
+	"nelhage.com/minigo/sgf"
+)
+
+type markJSON struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+type labelJSON struct {
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Text string `json:"text"`
+}
+
+type lineJSON struct {
+	From [2]int `json:"from"`
+	To   [2]int `json:"to"`
+}
+
+// annotationsJSON is the board.json representation of SGF annotations
+// and markup
+type annotationsJSON struct {
+	Comment           string      `json:"comment,omitempty"`
+	Name              string      `json:"name,omitempty"`
+	Quality           string      `json:"quality,omitempty"`
+	QualityEmphasis   int         `json:"quality_emphasis,omitempty"`
+	Judgement         string      `json:"judgement,omitempty"`
+	JudgementEmphasis int         `json:"judgement_emphasis,omitempty"`
+	Hotspot           bool        `json:"hotspot,omitempty"`
+	Marks             []markJSON  `json:"marks,omitempty"`
+	Labels            []labelJSON `json:"labels,omitempty"`
+	Arrows            []lineJSON  `json:"arrows,omitempty"`
+	Lines             []lineJSON  `json:"lines,omitempty"`
+}
+
+func linesJSON(ls []sgf.Line) []lineJSON {
+	var out []lineJSON
+	for _, l := range ls {
+		out = append(out, lineJSON{
+			From: [2]int{l.From.X, l.From.Y},
+			To:   [2]int{l.To.X, l.To.Y},
+		})
+	}
+	return out
+}
+
+func newAnnotationsJSON(a *sgf.Annotations) *annotationsJSON {
+	if a == nil {
+		return nil
+	}
+	out := &annotationsJSON{
+		Comment:           a.Comment,
+		Name:              a.Name,
+		Quality:           a.Quality.String(),
+		QualityEmphasis:   a.QualityEmphasis,
+		Judgement:         a.Judgement.String(),
+		JudgementEmphasis: a.JudgementEmphasis,
+		Hotspot:           a.Hotspot,
+		Arrows:            linesJSON(a.Arrows),
+		Lines:             linesJSON(a.Lines),
+	}
+	for _, m := range a.Marks {
+		out.Marks = append(out.Marks, markJSON{m.Type.String(), m.X, m.Y})
+	}
+	for _, l := range a.Labels {
+		out.Labels = append(out.Labels, labelJSON{l.X, l.Y, l.Text})
+	}
+	return out
+}
+
+func pointsJSON(pts []sgf.Point) [][2]int {
+	var out [][2]int
+	for _, p := range pts {
+		out = append(out, [2]int{p.X, p.Y})
+	}
+	return out
+}
+
+// vectorJSON lists the points set in a board's bit vector
+func vectorJSON(v *bit.Vector, width int) [][2]int {
+	var out [][2]int
+	for i := 0; i < v.Len(); i++ {
+		if v.At(i) {
+			out = append(out, [2]int{i % width, i / width})
+		}
+	}
+	return out
+}
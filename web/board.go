@@ -0,0 +1,256 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nelhage.com/minigo/clock"
+	"nelhage.com/minigo/game"
+)
+
+// Versions of the board.json schema. Version 1 lists the stones in a
+// map from "x,y" to color; version 2 encodes them as an array of
+// numbers.
+const (
+	boardVersion1 = 1
+	boardVersion2 = 2
+
+	latestBoardVersion = boardVersion2
+)
+
+// Stone values in a version 2 board's stones array
+const (
+	stoneEmpty = 0
+	stoneBlack = 1
+	stoneWhite = 2
+)
+
+type boardJSON struct {
+	Version    int    `json:"version"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	ToMove     string `json:"to_move"`
+	MoveNumber int    `json:"move_number"`
+	// Positions is set in version 1, to a map[string]string from
+	// "x,y" to the color of the stone there. It is an interface so
+	// that an empty map is still encoded.
+	Positions interface{} `json:"positions,omitempty"`
+	// Stones is set in version 2, to the color of each point of
+	// the board in row-major order, using the stone* values
+	Stones []int `json:"stones,omitempty"`
+
+	LastMove  *lastMoveJSON  `json:"last_move,omitempty"`
+	Ko        *[2]int        `json:"ko,omitempty"`
+	Prisoners map[string]int `json:"prisoners"`
+	Passes    int            `json:"passes"`
+	GameOver  bool           `json:"game_over"`
+	// Result is the game's result, in SGF's format, once it is
+	// over
+	Result  string            `json:"result,omitempty"`
+	Players map[string]string `json:"players,omitempty"`
+	Komi    float64           `json:"komi"`
+	// Seats reports which colors players have claimed, if the
+	// server assigns seats
+	Seats map[string]bool `json:"seats,omitempty"`
+	Clock *clockJSON      `json:"clock,omitempty"`
+
+	Annotations *annotationsJSON `json:"annotations,omitempty"`
+	Dimmed      [][2]int         `json:"dimmed,omitempty"`
+	Problem     *problemJSON     `json:"problem,omitempty"`
+	// Alive and Dead suggest the status of stones once the game
+	// is over
+	Alive [][2]int `json:"alive,omitempty"`
+	Dead  [][2]int `json:"dead,omitempty"`
+	// Ladders holds a stone of each chain in a working ladder
+	Ladders [][2]int `json:"ladders,omitempty"`
+}
+
+// clockJSON reports a timed game's clocks. Times are in seconds.
+type clockJSON struct {
+	Kind     string `json:"kind"`
+	Overtime string `json:"overtime,omitempty"`
+	// Running is the player whose clock is running, if any
+	Running string                     `json:"running,omitempty"`
+	Players map[string]playerClockJSON `json:"players"`
+}
+
+type playerClockJSON struct {
+	Main     float64 `json:"main"`
+	Overtime bool    `json:"overtime"`
+	Period   float64 `json:"period,omitempty"`
+	Periods  int     `json:"periods,omitempty"`
+	Stones   int     `json:"stones,omitempty"`
+	Flagged  bool    `json:"flagged"`
+}
+
+func newClockJSON(c *clock.Clock, at time.Time) *clockJSON {
+	out := &clockJSON{
+		Kind:     c.Control.Kind.String(),
+		Overtime: c.Control.Overtime(),
+		Players:  make(map[string]playerClockJSON),
+	}
+	if p, ok := c.Running(); ok {
+		out.Running = colorStr(p)
+	}
+	for _, p := range []game.Color{game.Black, game.White} {
+		st := c.State(p, at)
+		out.Players[colorStr(p)] = playerClockJSON{
+			Main:     st.Main.Seconds(),
+			Overtime: st.Overtime,
+			Period:   st.Period.Seconds(),
+			Periods:  st.Periods,
+			Stones:   st.Stones,
+			Flagged:  st.Flagged,
+		}
+	}
+	return out
+}
+
+type lastMoveJSON struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Color string `json:"color"`
+	Pass  bool   `json:"pass,omitempty"`
+}
+
+// serveBoard returns the current position. The optional `version`
+// parameter selects the schema; it defaults to version 1.
+func (s *Server) serveBoard(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	version := boardVersion1
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < boardVersion1 || n > latestBoardVersion {
+			return nil, badRequest("unknown board version %q", v)
+		}
+		version = n
+	}
+
+	g := s.game
+	out := &boardJSON{
+		Version:    version,
+		Width:      g.Width,
+		Height:     g.Height,
+		ToMove:     colorStr(g.ToPlay()),
+		MoveNumber: g.MoveNumber(),
+		Passes:     g.Passes(),
+		GameOver:   g.GameOver(),
+		Komi:       g.Info.Komi,
+	}
+	var positions map[string]string
+	if version == boardVersion1 {
+		positions = make(map[string]string)
+		out.Positions = positions
+	} else {
+		out.Stones = make([]int, g.Width*g.Height)
+	}
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			c, ok := g.At(x, y)
+			if !ok {
+				continue
+			}
+			if out.Stones != nil {
+				out.Stones[y*g.Width+x] = stoneValue(c)
+			} else {
+				positions[fmt.Sprintf("%d,%d", x, y)] = colorStr(c)
+			}
+		}
+	}
+
+	if x, y, c, ok := g.LastMove(); ok {
+		out.LastMove = &lastMoveJSON{X: x, Y: y, Color: colorStr(c), Pass: x < 0}
+	}
+	if x, y, ok := g.KoPoint(); ok {
+		out.Ko = &[2]int{x, y}
+	}
+	black, white := g.Prisoners()
+	out.Prisoners = map[string]int{"B": black, "W": white}
+	if g.Info.Black != "" || g.Info.White != "" {
+		out.Players = map[string]string{"B": g.Info.Black, "W": g.Info.White}
+	}
+
+	out.Seats = s.seatsJSON()
+	if s.clock != nil {
+		now := s.now()
+		out.Clock = newClockJSON(s.clock, now)
+		// A player who has run out of time loses, even if
+		// that is not recorded until the next change to the
+		// game
+		if c, ok := s.clock.Flagged(now); ok && !out.GameOver {
+			out.GameOver = true
+			out.Result = "B+T"
+			if c == game.Black {
+				out.Result = "W+T"
+			}
+		}
+	}
+
+	out.Annotations = newAnnotationsJSON(g.Annotations())
+	out.Dimmed = pointsJSON(g.Dimmed())
+	out.Ladders = s.ladders.get(g)
+	if s.training != nil {
+		out.Problem = s.training.problemJSON()
+	}
+	if out.GameOver {
+		alive, dead := g.LifeStatus()
+		out.Alive = vectorJSON(alive, g.Width)
+		out.Dead = vectorJSON(dead, g.Width)
+		if out.Result == "" {
+			out.Result = g.Info.Result
+		}
+		if out.Result == "" {
+			out.Result = g.Score(scoringRules(g.Info.Rules), dead).Result(g.Info.Komi)
+		}
+	}
+	return out, nil
+}
+
+// ladderCache remembers the ladders in the last position they were read
+// for. Reading them is the slowest part of serving board.json, which
+// clients poll while the position stays the same.
+type ladderCache struct {
+	mu  sync.Mutex
+	pos *game.Position
+	ko  [2]int
+	// ladders is the pointsJSON of the position's ladders
+	ladders [][2]int
+}
+
+// get returns the ladders in g's position, reading them if it differs
+// from the last one
+func (c *ladderCache) get(g *game.Game) [][2]int {
+	pos := g.Position()
+	ko := [2]int{-1, -1}
+	if x, y, ok := g.KoPoint(); ok {
+		ko = [2]int{x, y}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pos == nil || !c.pos.Equal(pos) || c.ko != ko {
+		c.pos, c.ko, c.ladders = pos, ko, pointsJSON(g.Ladders())
+	}
+	return c.ladders
+}
+
+func stoneValue(c game.Color) int {
+	if c == game.White {
+		return stoneWhite
+	}
+	return stoneBlack
+}
+
+// scoringRules returns the rules to count a game by, given the name of
+// its rules in SGF's RU property. Only Japanese and Korean rules count
+// territory.
+func scoringRules(ru string) game.Rules {
+	switch strings.ToLower(ru) {
+	case "japanese", "korean":
+		return game.TerritoryRules
+	default:
+		return game.AreaRules
+	}
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func decodeBoard(t *testing.T, h http.Handler, path string) map[string]interface{} {
+	t.Helper()
+	w := request(h, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d: %s", path, w.Code, w.Body)
+	}
+	var out map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBoardVersions(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 3})
+	v1 := decodeBoard(t, h, "/board.json")
+	if v1["version"] != 1.0 || !reflect.DeepEqual(v1["positions"], map[string]interface{}{}) {
+		t.Errorf("empty board: %v", v1)
+	}
+	if _, ok := v1["stones"]; ok {
+		t.Errorf("version 1 has stones")
+	}
+
+	postMove(h, 1, 0, "B", 0)
+	postMove(h, 2, 1, "W", 1)
+	v1 = decodeBoard(t, h, "/board.json?version=1")
+	want := map[string]interface{}{"1,0": "B", "2,1": "W"}
+	if !reflect.DeepEqual(v1["positions"], want) {
+		t.Errorf("positions: %v", v1["positions"])
+	}
+
+	v2 := decodeBoard(t, h, "/board.json?version=2")
+	if _, ok := v2["positions"]; ok || v2["version"] != 2.0 {
+		t.Errorf("version 2: %v", v2)
+	}
+	stones := []interface{}{0.0, 1.0, 0.0, 0.0, 0.0, 2.0, 0.0, 0.0, 0.0}
+	if !reflect.DeepEqual(v2["stones"], stones) {
+		t.Errorf("stones: %v", v2["stones"])
+	}
+
+	if w := request(h, "GET", "/board.json?version=3", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("version 3: status %d", w.Code)
+	}
+}
+
+func TestBoardState(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 5})
+	// White captures at (2,1), leaving a ko at (1,1)
+	moves := [][2]int{{1, 0}, {2, 0}, {0, 1}, {3, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}
+	for i, m := range moves {
+		if code := postMove(h, m[0], m[1], []string{"B", "W"}[i%2], i); code != 200 {
+			t.Fatalf("move %v: %d", m, code)
+		}
+	}
+	var b boardJSON
+	w := request(h, "GET", "/board.json?version=2", nil)
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.LastMove == nil || *b.LastMove != (lastMoveJSON{X: 1, Y: 1, Color: "W"}) {
+		t.Errorf("last move: %+v", b.LastMove)
+	}
+	if b.Ko == nil || *b.Ko != [2]int{2, 1} {
+		t.Errorf("ko: %v", b.Ko)
+	}
+	if !reflect.DeepEqual(b.Prisoners, map[string]int{"B": 0, "W": 1}) {
+		t.Errorf("prisoners: %v", b.Prisoners)
+	}
+	if b.GameOver || b.Result != "" || b.Passes != 0 {
+		t.Errorf("game over: %+v", b)
+	}
+
+	postMove(h, -1, -1, "B", 8)
+	postMove(h, -1, -1, "W", 9)
+	w = request(h, "GET", "/board.json?version=2", nil)
+	b = boardJSON{}
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !b.GameOver || b.Passes != 2 || b.Result == "" || b.Ko != nil {
+		t.Errorf("after passes: %+v", b)
+	}
+	if b.LastMove == nil || !b.LastMove.Pass || b.LastMove.Color != "W" {
+		t.Errorf("last move: %+v", b.LastMove)
+	}
+}
+
+func TestBoardPlayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.sgf")
+	sgf := "(;FF[4]SZ[9]PB[Black Player]PW[White Player]KM[6.5]RE[W+R];B[ee];W[])"
+	if err := os.WriteFile(path, []byte(sgf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestServer(t, &Config{Load: path})
+	var b boardJSON
+	w := request(h, "GET", "/board.json", nil)
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b.Players, map[string]string{"B": "Black Player", "W": "White Player"}) {
+		t.Errorf("players: %v", b.Players)
+	}
+	if b.Komi != 6.5 || b.Passes != 1 || b.GameOver {
+		t.Errorf("board: %+v", b)
+	}
+}
+
+func TestBoardLadders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ladder.sgf")
+	sgf := "(;FF[4]SZ[9]AB[ha][gb][ib][ic]AW[hb]PL[W])"
+	if err := os.WriteFile(path, []byte(sgf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestServer(t, &Config{Load: path})
+	for i := 0; i < 2; i++ {
+		b := decodeBoard(t, h, "/board.json")
+		if !reflect.DeepEqual(b["ladders"], []interface{}{[]interface{}{7.0, 1.0}}) {
+			t.Errorf("request %d: ladders %v", i, b["ladders"])
+		}
+	}
+	// With black to play, the stone is simply captured
+	if code := postMove(h, 2, 5, "W", 0); code != 200 {
+		t.Fatalf("move: %d", code)
+	}
+	if b := decodeBoard(t, h, "/board.json"); b["ladders"] != nil {
+		t.Errorf("after move: ladders %v", b["ladders"])
+	}
+}
@@ -1,14 +1,98 @@
 package web
 
+import (
+	"fmt"
+	"net/http"
+
+	"nelhage.com/minigo/game"
+)
+
+// ErrorCode is a machine-readable reason for a failed request
+type ErrorCode string
+
+const (
+	// CodeBadRequest means the request was malformed or made no
+	// sense in the server's current state
+	CodeBadRequest ErrorCode = "bad_request"
+
+	// CodeStalePosition means the request was made against a
+	// position that has since changed
+	CodeStalePosition ErrorCode = "stale_position"
+	// CodeNotYourTurn means a move was made out of turn
+	CodeNotYourTurn ErrorCode = "not_your_turn"
+	// CodeUnauthorized means a request that acts for a player
+	// lacked a valid token for a seat
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodeSeatTaken means a player tried to claim a seat that
+	// another player holds
+	CodeSeatTaken ErrorCode = "seat_taken"
+
+	// CodeOutOfBounds, CodeOccupied, CodeSelfCapture, CodeKo and
+	// CodeGameOver correspond to the errors of the same names in
+	// package game
+	CodeOutOfBounds ErrorCode = "out_of_bounds"
+	CodeOccupied    ErrorCode = "occupied"
+	CodeSelfCapture ErrorCode = "self_capture"
+	CodeKo          ErrorCode = "ko"
+	CodeGameOver    ErrorCode = "game_over"
+	// CodeProblemFinished means a move was made in a
+	// life-and-death problem that has already been solved or
+	// failed
+	CodeProblemFinished ErrorCode = "problem_finished"
+)
+
 // UserError represents an error to be returned in a user-visible
 // manner
 type UserError struct {
-	Err string `json:"error"`
+	// Err is a human-readable message
+	Err    string    `json:"error"`
+	Reason ErrorCode `json:"code"`
+	// Point is the move that was rejected, if any
+	Point *[2]int `json:"point,omitempty"`
+	// MoveNumber is the current move number, for a stale request
+	MoveNumber *int `json:"move_number,omitempty"`
+}
+
+// badRequest returns a UserError with code CodeBadRequest
+func badRequest(format string, args ...interface{}) *UserError {
+	return &UserError{Err: fmt.Sprintf(format, args...), Reason: CodeBadRequest}
+}
+
+// moveErrors maps the errors returned by game.Move to their codes and
+// messages
+var moveErrors = map[error]struct {
+	code ErrorCode
+	msg  string
+}{
+	game.ErrOutOfBounds: {CodeOutOfBounds, "that point is off the board"},
+	game.ErrOccupied:    {CodeOccupied, "there is already a stone there"},
+	game.ErrSelfCapture: {CodeSelfCapture, "that move would capture your own stones"},
+	game.ErrKo:          {CodeKo, "that move would retake the ko immediately"},
+	game.ErrGameOver:    {CodeGameOver, "the game is over"},
+}
+
+// moveError converts an error from a move at (x,y) into a UserError.
+// Errors not caused by the move are returned unchanged.
+func moveError(err error, x, y int) error {
+	e, ok := moveErrors[err]
+	if !ok {
+		return err
+	}
+	return &UserError{Err: e.msg, Reason: e.code, Point: &[2]int{x, y}}
 }
 
 // Code returns the HTTP status code this error should return
-func (*UserError) Code() int {
-	return 400
+func (ue *UserError) Code() int {
+	switch ue.Reason {
+	case CodeStalePosition, CodeNotYourTurn, CodeSeatTaken:
+		return http.StatusConflict
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeOutOfBounds, CodeOccupied, CodeSelfCapture, CodeKo, CodeGameOver, CodeProblemFinished:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
 }
 
 // Error implements the error interface
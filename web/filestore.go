@@ -0,0 +1,204 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"nelhage.com/minigo/sgf"
+)
+
+// FileStore is a Store that keeps each game in a directory as an
+// append-only log, "<id>.log". The log's first line is a JSON header
+// holding the starting position; each further line is a JSON Event.
+// Every write is synced to disk before it is acknowledged. A file
+// named "current" holds the id of the current game.
+//
+// A crash in the middle of an append can leave a partial last line.
+// Current discards it, since the event it held was never acknowledged.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+type logHeader struct {
+	ID    string        `json:"id"`
+	Start *sgf.GameTree `json:"start"`
+}
+
+// NewFileStore returns a FileStore keeping games in dir, creating it
+// if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) logPath(id string) string {
+	return filepath.Join(f.dir, id+".log")
+}
+
+// Create implements Store
+func (f *FileStore) Create(id string, start *sgf.GameTree) error {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("bad game id %q", id)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(f.logPath(id)); err == nil {
+		return fmt.Errorf("game %s already exists", id)
+	}
+	header, err := json.Marshal(&logHeader{ID: id, Start: start})
+	if err != nil {
+		return err
+	}
+	if err := f.writeFile(f.logPath(id), append(header, '\n')); err != nil {
+		return err
+	}
+	return f.writeFile(filepath.Join(f.dir, "current"), []byte(id+"\n"))
+}
+
+// writeFile replaces the file at path with data, so that after a
+// crash it holds either the old contents or the new
+func (f *FileStore) writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return f.syncDir()
+}
+
+func (f *FileStore) syncDir() error {
+	d, err := os.Open(f.dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Append implements Store
+func (f *FileStore) Append(id string, e Event) error {
+	line, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.logPath(id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	// Note the size first, so that a failed append can be undone
+	// rather than left for a later append to run on from
+	fi, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	_, err = out.Write(append(line, '\n'))
+	if err == nil {
+		err = out.Sync()
+	}
+	if err != nil {
+		out.Truncate(fi.Size())
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Current implements Store
+func (f *FileStore) Current() (*StoredGame, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buf, err := os.ReadFile(filepath.Join(f.dir, "current"))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	id := strings.TrimSpace(string(buf))
+	g, err := f.load(id)
+	if err != nil {
+		return nil, false, err
+	}
+	return g, true, nil
+}
+
+// load reads a game's log, truncating a partial last line
+func (f *FileStore) load(id string) (*StoredGame, error) {
+	path := f.logPath(id)
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	r := bufio.NewReader(in)
+	var (
+		g    *StoredGame
+		good int64
+	)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// A torn write; drop it
+				if err := os.Truncate(path, good); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		good += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if g == nil {
+			var h logHeader
+			if err := json.Unmarshal(line, &h); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+			if h.ID != id || h.Start == nil {
+				return nil, fmt.Errorf("%s:%d: bad header", path, n)
+			}
+			g = &StoredGame{ID: id, Start: h.Start}
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		g.Events = append(g.Events, e)
+	}
+	if g == nil {
+		return nil, fmt.Errorf("%s: missing header", path)
+	}
+	return g, nil
+}
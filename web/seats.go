@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"nelhage.com/minigo/game"
+)
+
+// Seats let each player claim a color. Claiming a seat returns a
+// token of the form "<color>.<game id>.<signature>", where the
+// signature is an HMAC-SHA256 of the rest under Config.Secret. The
+// token is sent back as a bearer token in the Authorization header of
+// each request that acts for the player.
+
+type seatKey struct{}
+
+// newGameID returns a random identifier for a game, so that tokens
+// for one game are not accepted for another
+func newGameID() (string, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
+
+func (s *Server) seatsEnabled() bool {
+	return s.c.Secret != ""
+}
+
+func (s *Server) sign(msg string) string {
+	mac := hmac.New(sha256.New, []byte(s.c.Secret))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// token returns the token for c's seat in the current game
+func (s *Server) token(c game.Color) string {
+	msg := colorStr(c) + "." + s.gameID
+	return msg + "." + s.sign(msg)
+}
+
+// checkToken returns the color of the seat a token is for
+func (s *Server) checkToken(token string) (game.Color, error) {
+	unauthorized := &UserError{Err: "a valid player token is required", Reason: CodeUnauthorized}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[1] != s.gameID {
+		return game.Black, unauthorized
+	}
+	want := s.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(want)) {
+		return game.Black, unauthorized
+	}
+	switch parts[0] {
+	case "B":
+		return game.Black, nil
+	case "W":
+		return game.White, nil
+	}
+	return game.Black, unauthorized
+}
+
+// seated wraps a handler that acts for a player. If seats are enabled,
+// requests without a valid token are refused, and the handler can
+// find the player's color with seatColor. Otherwise anyone may act for
+// either player.
+func (s *Server) seated(handler handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if !s.seatsEnabled() {
+			return handler(w, r)
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		c, err := s.checkToken(token)
+		if err != nil {
+			return nil, err
+		}
+		return handler(w, r.WithContext(context.WithValue(r.Context(), seatKey{}, c)))
+	}
+}
+
+// seatColor returns the color of the player making a request, if
+// seats are enabled
+func seatColor(r *http.Request) (game.Color, bool) {
+	c, ok := r.Context().Value(seatKey{}).(game.Color)
+	return c, ok
+}
+
+// handleJoin claims a seat, returning its token
+func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if !s.seatsEnabled() {
+		return nil, badRequest("this server does not assign seats")
+	}
+	var args struct {
+		Color string `json:"color"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	c, ok := parseColor(args.Color)
+	if !ok {
+		return nil, badRequest("no such color: %q", args.Color)
+	}
+	if s.seats[c] {
+		return nil, &UserError{Err: "that seat is taken", Reason: CodeSeatTaken}
+	}
+	if s.training != nil {
+		s.seats[c] = true
+	} else if err := s.apply(Event{Kind: EventJoin, Color: args.Color}); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Color string `json:"color"`
+		Token string `json:"token"`
+	}{args.Color, s.token(c)}, nil
+}
+
+// seatsJSON reports which seats have been claimed, if seats are
+// enabled
+func (s *Server) seatsJSON() map[string]bool {
+	if !s.seatsEnabled() {
+		return nil
+	}
+	return map[string]bool{"B": s.seats[game.Black], "W": s.seats[game.White]}
+}
+
+// handleResign resigns the game for the requesting player, or for the
+// player to play if seats are not enabled
+func (s *Server) handleResign(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.training != nil {
+		return nil, badRequest("a problem cannot be resigned")
+	}
+	c, ok := seatColor(r)
+	if !ok {
+		c = s.game.ToPlay()
+	}
+	if err := s.apply(Event{Kind: EventResign, Color: colorStr(c)}); err == game.ErrGameOver {
+		return nil, &UserError{Err: "the game is over", Reason: CodeGameOver}
+	} else if err != nil {
+		return nil, err
+	}
+	return s.serveBoard(w, r)
+}
+
+// handleUndo takes back the last move. A seated player may only take
+// back their own move, and a resignation only with their opponent's
+// agreement: the player who resigned cannot take it back, but the
+// winner can.
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.training != nil {
+		return nil, badRequest("moves in a problem cannot be taken back")
+	}
+	var args struct {
+		MoveNumber *int `json:"move_number"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil && err != io.EOF {
+		return nil, badRequest("%v", err)
+	}
+	if err := s.checkMoveNumber(args.MoveNumber); err != nil {
+		return nil, err
+	}
+	if c, ok := seatColor(r); ok {
+		if loser, ok := s.game.Resigned(); ok {
+			if c == loser {
+				return nil, &UserError{Err: "only your opponent can take back your resignation", Reason: CodeNotYourTurn}
+			}
+		} else if _, _, last, ok := s.game.LastMove(); !ok || c != last {
+			return nil, &UserError{Err: "you can only take back your own move", Reason: CodeNotYourTurn}
+		}
+	}
+	if err := s.apply(Event{Kind: EventUndo}); err == errNothingToUndo {
+		return nil, badRequest("%v", err)
+	} else if err == errLostOnTime {
+		return nil, &UserError{Err: err.Error(), Reason: CodeGameOver}
+	} else if err != nil {
+		return nil, err
+	}
+	return s.serveBoard(w, r)
+}
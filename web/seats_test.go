@@ -0,0 +1,142 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func join(t *testing.T, h http.Handler, color string) string {
+	t.Helper()
+	w := request(h, "POST", "/join", map[string]string{"color": color})
+	if w.Code != http.StatusOK {
+		t.Fatalf("join %s: status %d: %s", color, w.Code, w.Body)
+	}
+	var out struct{ Token string }
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Token
+}
+
+func move(h http.Handler, token string, x, y int, toMove string) int {
+	return requestAs(h, token, "POST", "/move", map[string]interface{}{
+		"x": x, "y": y, "to_move": toMove,
+	}).Code
+}
+
+func TestSeats(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 9, Secret: "sekrit"})
+	if code := move(h, "", 4, 4, "B"); code != http.StatusUnauthorized {
+		t.Errorf("move without a token: %d", code)
+	}
+	black := join(t, h, "B")
+	if w := request(h, "POST", "/join", map[string]string{"color": "B"}); w.Code != http.StatusConflict {
+		t.Errorf("second join: %d", w.Code)
+	}
+	white := join(t, h, "W")
+
+	if code := move(h, white, 4, 4, "B"); code != http.StatusConflict {
+		t.Errorf("white moving for black: %d", code)
+	}
+	if code := move(h, black, 4, 4, "B"); code != http.StatusOK {
+		t.Errorf("black's move: %d", code)
+	}
+	forged := strings.Replace(black, "B.", "W.", 1)
+	if code := move(h, forged, 3, 3, "W"); code != http.StatusUnauthorized {
+		t.Errorf("forged token: %d", code)
+	}
+	other := newTestServer(t, &Config{Size: 9, Secret: "sekrit"})
+	if code := move(other, white, 3, 3, "W"); code != http.StatusUnauthorized {
+		t.Errorf("token from another game: %d", code)
+	}
+
+	// Black may take back the move while it is white's turn;
+	// white may not
+	if w := requestAs(h, white, "POST", "/undo", map[string]int{}); w.Code != http.StatusConflict {
+		t.Errorf("white undoing black's move: %d", w.Code)
+	}
+	if w := request(h, "POST", "/undo", map[string]int{}); w.Code != http.StatusUnauthorized {
+		t.Errorf("spectator undo: %d", w.Code)
+	}
+	if w := requestAs(h, black, "POST", "/undo", map[string]int{"move_number": 1}); w.Code != http.StatusOK {
+		t.Errorf("undo: %d: %s", w.Code, w.Body)
+	}
+
+	if w := request(h, "POST", "/resign", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("spectator resign: %d", w.Code)
+	}
+	w := requestAs(h, white, "POST", "/resign", nil)
+	var b boardJSON
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !b.GameOver || b.Result != "B+R" {
+		t.Errorf("resign: %d, over=%v result=%q", w.Code, b.GameOver, b.Result)
+	}
+	if b.Seats["B"] != true || b.Seats["W"] != true {
+		t.Errorf("seats: %v", b.Seats)
+	}
+
+	// White resigned on black's turn, but may not take it back;
+	// black may
+	if w := requestAs(h, white, "POST", "/undo", map[string]int{}); w.Code != http.StatusConflict {
+		t.Errorf("white undoing the resignation: %d", w.Code)
+	}
+	if w := requestAs(h, black, "POST", "/undo", map[string]int{}); w.Code != http.StatusOK {
+		t.Errorf("black undoing the resignation: %d: %s", w.Code, w.Body)
+	}
+
+	// Nor may black take back a resignation on black's turn
+	if w := requestAs(h, black, "POST", "/resign", nil); w.Code != http.StatusOK {
+		t.Errorf("black resign: %d", w.Code)
+	}
+	if w := requestAs(h, black, "POST", "/undo", map[string]int{}); w.Code != http.StatusConflict {
+		t.Errorf("black undoing the resignation: %d", w.Code)
+	}
+	if b := getBoard(t, h); b == nil || b.MoveNumber != 0 {
+		t.Errorf("board: %+v", b)
+	}
+}
+
+func TestOpenSeats(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 9})
+	if w := request(h, "POST", "/join", map[string]string{"color": "B"}); w.Code != http.StatusBadRequest {
+		t.Errorf("join: %d", w.Code)
+	}
+	if code := move(h, "", 4, 4, "B"); code != http.StatusOK {
+		t.Errorf("move: %d", code)
+	}
+	if w := request(h, "POST", "/undo", map[string]int{}); w.Code != http.StatusOK {
+		t.Errorf("undo: %d", w.Code)
+	}
+	if w := request(h, "POST", "/undo", map[string]int{}); w.Code != http.StatusBadRequest {
+		t.Errorf("undo at the start: %d", w.Code)
+	}
+	if w := request(h, "POST", "/resign", nil); w.Code != http.StatusOK {
+		t.Errorf("resign: %d", w.Code)
+	}
+	if w := request(h, "POST", "/resign", nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("resign twice: %d", w.Code)
+	}
+}
+
+func TestSeatsTraining(t *testing.T) {
+	h := newTestServer(t, &Config{
+		Problems: "../tsumego/testdata/goproblems.sgf",
+		Progress: filepath.Join(t.TempDir(), "progress.json"),
+		Secret:   "sekrit",
+	})
+	if code := move(h, "", 0, 7, "B"); code != http.StatusUnauthorized {
+		t.Errorf("move without a token: %d", code)
+	}
+	black := join(t, h, "B")
+	if code := move(h, black, 0, 7, "B"); code != http.StatusOK {
+		t.Errorf("move with a token: %d", code)
+	}
+	if w := requestAs(h, black, "POST", "/problem", map[string]int{"index": 0}); w.Code != http.StatusOK {
+		t.Errorf("restart problem: %d", w.Code)
+	}
+}
@@ -5,8 +5,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
+	"nelhage.com/minigo/book"
+	"nelhage.com/minigo/clock"
 	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
 )
 
 // DefaultSize is the default board size if none is provided
@@ -16,31 +23,241 @@ const DefaultSize = 9
 type Config struct {
 	Public string
 	Size   int
+	// Width and Height override Size to request a rectangular
+	// board
+	Width, Height int
+	// Load is the path to an SGF file whose first game is loaded
+	// at startup instead of starting a new game
+	Load string
+	// Book is the path to an opening book, built by `minigo book
+	// build', to suggest moves from
+	Book string
+	// Problems is the path to an SGF collection of life-and-death
+	// problems. If it is set, the server presents the problems
+	// instead of a free game.
+	Problems string
+	// Progress is the path where results on the problems are
+	// kept. It defaults to the problems' path with
+	// ".progress.json" appended.
+	Progress string
+	// Secret is the key seat tokens are signed with. If it is
+	// empty, players do not claim seats, and anyone may play
+	// either color.
+	Secret string
+	// Data is a directory to store games in, so that the game in
+	// progress survives a restart. If it is empty, games are
+	// kept in memory.
+	Data string
+	// Store, if set, stores games instead of Data
+	Store Store
+	// Clock, if set, is the time control for games. A player who
+	// runs out of time loses.
+	Clock *clock.Control
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
 }
 
 // Server implements a web server for playing Go
 type Server struct {
 	c Config
 
+	// mu guards the fields below. Handlers that only read hold
+	// it for reading; those that change the game hold it for
+	// writing.
+	mu   sync.RWMutex
 	game *game.Game
+	// record is the collection loaded from Config.Load, if any
+	record *sgf.Collection
+	book   *book.Book
+	// training is set when serving Config.Problems
+	training *training
+	// gameID identifies the game seat tokens are issued for, and
+	// seats records which colors have been claimed
+	gameID string
+	seats  map[game.Color]bool
+	// store records the current game, unless the server is
+	// serving problems
+	store Store
+	// clock is the current game's clock, if it is timed
+	clock *clock.Clock
+	now   func() time.Time
+
+	// ladders caches the ladders in the current position. It has
+	// its own lock, since board.json holds mu only for reading.
+	ladders ladderCache
 }
 
 // Init configures a server and initializes any relevant
 func (s *Server) Init(c *Config) error {
 	s.c = *c
+	s.now = s.c.Now
+	if s.now == nil {
+		s.now = time.Now
+	}
 	size := s.c.Size
 	if size == 0 {
 		size = DefaultSize
 	}
-	s.game = game.New(size)
+	width, height := s.c.Width, s.c.Height
+	if width == 0 {
+		width = size
+	}
+	if height == 0 {
+		height = size
+	}
+	if width < 1 || width > sgf.MaxBoardSize || height < 1 || height > sgf.MaxBoardSize {
+		return fmt.Errorf("bad board size %dx%d: must be 1 to %d on each side", width, height, sgf.MaxBoardSize)
+	}
+	s.game = game.NewRect(width, height)
+	s.seats = make(map[game.Color]bool)
+
+	if s.c.Load != "" {
+		g, c, err := loadSGF(s.c.Load)
+		if err != nil {
+			return err
+		}
+		s.game, s.record = g, c
+	}
+
+	if s.c.Book != "" {
+		b, err := book.Load(s.c.Book)
+		if err != nil {
+			return err
+		}
+		s.book = b
+	}
+
+	if s.c.Problems != "" {
+		t, err := newTraining(s.c.Problems, s.c.Progress)
+		if err != nil {
+			return err
+		}
+		s.training = t
+		s.game = t.attempt.Game
+		// Problems are not stored, but players may still
+		// claim seats in them
+		s.gameID, err = newGameID()
+		return err
+	}
+
+	if s.c.Clock != nil {
+		if err := s.c.Clock.Validate(); err != nil {
+			return err
+		}
+		s.game.Info.TimeLimit = s.c.Clock.TimeLimit()
+		s.game.Info.Overtime = s.c.Clock.Overtime()
+		s.clock = clock.New(*s.c.Clock)
+	}
+
+	s.store = s.c.Store
+	if s.store == nil && s.c.Data != "" {
+		f, err := NewFileStore(s.c.Data)
+		if err != nil {
+			return err
+		}
+		s.store = f
+	} else if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	return s.recover()
+}
+
+// recover resumes the store's current game if it is still in
+// progress, and otherwise begins storing the configured game as a new
+// one
+func (s *Server) recover() error {
+	sg, ok, err := s.store.Current()
+	if err != nil {
+		return err
+	}
+	if ok {
+		g, seats, clk, err := sg.replay(s.c.Clock)
+		if err != nil {
+			return err
+		}
+		if !g.GameOver() {
+			s.game, s.seats, s.clock, s.gameID = g, seats, clk, sg.ID
+			return nil
+		}
+	}
+	id, err := newGameID()
+	if err != nil {
+		return err
+	}
+	s.gameID = id
+	return s.store.Create(id, s.game.SGF())
+}
 
+// apply makes the change an event records to the current game, as of
+// now. If the player to play has run out of time, their loss is
+// recorded first.
+func (s *Server) apply(e Event) error {
+	e.At = s.now()
+	if s.clock != nil && !s.game.GameOver() {
+		if c, ok := s.clock.Flagged(e.At); ok {
+			err := s.commit(Event{Kind: EventTimeout, Color: colorStr(c), At: e.At})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.commit(e)
+}
+
+// commit makes the change an event records. The event is tried on a
+// copy of the game, and takes effect only once it is stored.
+func (s *Server) commit(e Event) error {
+	g := s.game.Copy()
+	seats := make(map[game.Color]bool)
+	for c, ok := range s.seats {
+		seats[c] = ok
+	}
+	var clk *clock.Clock
+	if s.clock != nil {
+		c := *s.clock
+		clk = &c
+	}
+	if err := e.apply(g, seats, clk); err != nil {
+		return err
+	}
+	if err := s.store.Append(s.gameID, e); err != nil {
+		return err
+	}
+	s.game, s.seats, s.clock = g, seats, clk
 	return nil
 }
 
+func loadSGF(path string) (*game.Game, *sgf.Collection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	c, err := sgf.ParseSGF(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	g, err := game.FromSGF(c.Trees[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return g, c, nil
+}
+
 // Bind configures routes in the provided http.ServeMux
 func (s *Server) Bind(mux *http.ServeMux) error {
-	mux.Handle("/board.json", s.handler(s.serveBoard))
-	mux.Handle("/move", s.handler(s.handleMove))
+	mux.Handle("/board.json", s.handler(s.read(s.serveBoard)))
+	mux.Handle("/join", s.handler(s.write(s.handleJoin)))
+	mux.Handle("/move", s.handler(s.seated(s.write(s.handleMove))))
+	mux.Handle("/resign", s.handler(s.seated(s.write(s.handleResign))))
+	mux.Handle("/undo", s.handler(s.seated(s.write(s.handleUndo))))
+	mux.Handle("/tree.json", s.handler(s.read(s.serveTree)))
+	mux.Handle("/record.json", s.handler(s.read(s.serveRecord)))
+	mux.Handle("/book.json", s.handler(s.read(s.serveBook)))
+	mux.Handle("/estimate", s.handler(s.read(s.serveEstimate)))
+	mux.Handle("/ladder.json", s.handler(s.read(s.serveLadder)))
+	mux.Handle("/problems.json", s.handler(s.read(s.serveProblems)))
+	mux.Handle("/problem", s.handler(s.seated(s.write(s.handleProblem))))
 	mux.Handle("/", http.FileServer(http.Dir(s.c.Public)))
 	return nil
 }
@@ -64,7 +281,27 @@ func replyJSON(w http.ResponseWriter, val interface{}) error {
 	return nil
 }
 
-func (s *Server) handler(handler func(http.ResponseWriter, *http.Request) (interface{}, error)) http.Handler {
+type handlerFunc func(http.ResponseWriter, *http.Request) (interface{}, error)
+
+// read wraps a handler that only reads the server's state
+func (s *Server) read(handler handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return handler(w, r)
+	}
+}
+
+// write wraps a handler that changes the server's state
+func (s *Server) write(handler handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return handler(w, r)
+	}
+}
+
+func (s *Server) handler(handler handlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if val, err := handler(w, r); err != nil {
 			log.Printf("error: %v", err)
@@ -83,43 +320,128 @@ func (s *Server) handler(handler func(http.ResponseWriter, *http.Request) (inter
 	})
 }
 
-func (s *Server) serveBoard(w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	var out struct {
-		ToMove    string            `json:"to_move"`
-		Positions map[string]string `json:"positions"`
-	}
-	out.Positions = make(map[string]string)
-	for x := 0; x < s.game.Size; x++ {
-		for y := 0; y < s.game.Size; y++ {
-			if c, ok := s.game.At(x, y); ok {
-				key := fmt.Sprintf("%d,%d", x, y)
-				out.Positions[key] = colorStr(c)
-			}
-		}
-	}
-
-	out.ToMove = colorStr(s.game.ToPlay())
-
-	return &out, nil
-}
-
 func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	var args struct {
 		X      int    `json:"x"`
 		Y      int    `json:"y"`
 		ToMove string `json:"to_move"`
+		// MoveNumber, if given, is the move number of the
+		// position the move was chosen in
+		MoveNumber *int `json:"move_number"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
-		return nil, &UserError{err.Error()}
+		return nil, badRequest("%v", err)
 	}
 
-	if args.ToMove != colorStr(s.game.ToPlay()) {
-		return nil, &UserError{"it's not your turn"}
+	if err := s.checkMoveNumber(args.MoveNumber); err != nil {
+		return nil, err
 	}
 
-	if err := s.game.Move(args.X, args.Y); err != nil {
-		return nil, &UserError{"illegal move"}
+	c, seated := seatColor(r)
+	if args.ToMove != colorStr(s.game.ToPlay()) || seated && c != s.game.ToPlay() {
+		return nil, &UserError{Err: "it's not your turn", Reason: CodeNotYourTurn}
+	}
+
+	if s.training != nil {
+		if err := s.training.play(args.X, args.Y); err != nil {
+			return nil, err
+		}
+	} else if err := s.apply(Event{Kind: EventMove, X: args.X, Y: args.Y}); err != nil {
+		return nil, moveError(err, args.X, args.Y)
 	}
 
 	return s.serveBoard(w, r)
 }
+
+// checkMoveNumber returns an error if a request was made against a
+// position other than the current one. n is the move number of the
+// position the request was made in, if the client gave one.
+func (s *Server) checkMoveNumber(n *int) error {
+	if cur := s.game.MoveNumber(); n != nil && *n != cur {
+		return &UserError{
+			Err:        fmt.Sprintf("the position has changed since move %d", *n),
+			Reason:     CodeStalePosition,
+			MoveNumber: &cur,
+		}
+	}
+	return nil
+}
+
+// serveTree returns the game so far as an SGF collection, in the JSON
+// form described in package sgf
+func (s *Server) serveTree(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return &sgf.Collection{Trees: []*sgf.GameTree{s.game.SGF()}}, nil
+}
+
+// serveRecord returns the collection loaded at startup, including any
+// variations and games beyond the first, for review
+func (s *Server) serveRecord(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.record == nil {
+		return nil, badRequest("no game record was loaded")
+	}
+	return s.record, nil
+}
+
+// serveBook returns the opening book's suggestions for the current
+// position
+func (s *Server) serveBook(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.book == nil {
+		return nil, badRequest("no opening book was loaded")
+	}
+	type candidate struct {
+		X       int     `json:"x"`
+		Y       int     `json:"y"`
+		Count   int     `json:"count"`
+		WinRate float64 `json:"win_rate"`
+	}
+	out := struct {
+		Candidates []candidate `json:"candidates"`
+	}{[]candidate{}}
+	for _, c := range s.book.Lookup(s.game) {
+		out.Candidates = append(out.Candidates, candidate{c.Move.X, c.Move.Y, c.Count, c.WinRate()})
+	}
+	return &out, nil
+}
+
+// serveEstimate returns an estimate of who owns each point of the
+// board, as rows of scores from 1 for black to -1 for white, and of
+// black's lead
+func (s *Server) serveEstimate(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	e := s.game.Estimate()
+	out := struct {
+		Width     int         `json:"width"`
+		Height    int         `json:"height"`
+		Ownership [][]float64 `json:"ownership"`
+		Margin    float64     `json:"margin"`
+	}{Width: e.Width, Height: e.Height, Margin: e.Margin}
+	for y := 0; y < e.Height; y++ {
+		out.Ownership = append(out.Ownership, e.Ownership[y*e.Width:(y+1)*e.Width])
+	}
+	return &out, nil
+}
+
+// serveLadder reads the ladder for the chain at the point given by
+// the x and y query parameters
+func (s *Server) serveLadder(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	x, err := strconv.Atoi(r.FormValue("x"))
+	if err != nil {
+		return nil, badRequest("bad x coordinate")
+	}
+	y, err := strconv.Atoi(r.FormValue("y"))
+	if err != nil {
+		return nil, badRequest("bad y coordinate")
+	}
+	if x < 0 || x >= s.game.Width || y < 0 || y >= s.game.Height {
+		return nil, badRequest("point is off the board")
+	}
+	l, ok := s.game.ReadLadder(x, y)
+	if !ok {
+		return nil, badRequest("no chain with one or two liberties there")
+	}
+	return &struct {
+		Captured bool     `json:"captured"`
+		Attacker string   `json:"attacker"`
+		First    string   `json:"first"`
+		Moves    [][2]int `json:"moves"`
+	}{l.Captured, colorStr(l.Attacker), colorStr(l.First), pointsJSON(l.Moves)}, nil
+}
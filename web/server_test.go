@@ -0,0 +1,315 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nelhage.com/minigo/clock"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestServer returns a handler for a new server. Tests call it
+// directly rather than through a listener, so that the race detector
+// does not mistake network I/O for synchronization.
+func newTestServer(t *testing.T, c *Config) http.Handler {
+	t.Helper()
+	if c.Public == "" {
+		c.Public = t.TempDir()
+	}
+	s := &Server{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Bind(mux)
+	return mux
+}
+
+func request(h http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	return requestAs(h, "", method, path, body)
+}
+
+// requestAs makes a request with a player's token
+func requestAs(h http.Handler, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var in io.Reader
+	if body != nil {
+		buf, _ := json.Marshal(body)
+		in = bytes.NewReader(buf)
+	}
+	r := httptest.NewRequest(method, path, in)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+type boardReply struct {
+	ToMove     string `json:"to_move"`
+	MoveNumber int    `json:"move_number"`
+}
+
+func getBoard(t *testing.T, h http.Handler) *boardReply {
+	w := request(h, "GET", "/board.json", nil)
+	var b boardReply
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Error(err)
+		return nil
+	}
+	return &b
+}
+
+func postMove(h http.Handler, x, y int, toMove string, number int) int {
+	return request(h, "POST", "/move", map[string]interface{}{
+		"x": x, "y": y, "to_move": toMove, "move_number": number,
+	}).Code
+}
+
+func TestInitSize(t *testing.T) {
+	for _, c := range []Config{{Size: -1}, {Size: 53}, {Width: 19, Height: -3}, {Size: 9, Width: 60}} {
+		s := &Server{}
+		if err := s.Init(&c); err == nil {
+			t.Errorf("%+v: accepted", c)
+		}
+	}
+	newTestServer(t, &Config{Size: 52})
+	newTestServer(t, &Config{Width: 1, Height: 19})
+}
+
+func TestMoveConflict(t *testing.T) {
+	h := newTestServer(t, &Config{})
+	if code := postMove(h, 2, 2, "B", 0); code != 200 {
+		t.Fatalf("first move: %d", code)
+	}
+	if code := postMove(h, 3, 3, "W", 0); code != http.StatusConflict {
+		t.Errorf("stale move: %d, want 409", code)
+	}
+	if code := postMove(h, 3, 3, "W", 1); code != 200 {
+		t.Errorf("current move: %d", code)
+	}
+	if b := getBoard(t, h); b.MoveNumber != 2 {
+		t.Errorf("move number %d", b.MoveNumber)
+	}
+}
+
+// TestConcurrentRequests plays moves from several clients at once
+// while others read the board. Run it with -race.
+func TestConcurrentRequests(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 9})
+	const players = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	played := 0
+	for p := 0; p < players; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				b := getBoard(t, h)
+				if b == nil {
+					return
+				}
+				idx := (p*20 + i*7) % 81
+				switch code := postMove(h, idx%9, idx/9, b.ToMove, b.MoveNumber); code {
+				case 200:
+					mu.Lock()
+					played++
+					mu.Unlock()
+				case http.StatusUnprocessableEntity, http.StatusConflict:
+				default:
+					t.Errorf("move: status %d", code)
+				}
+			}
+		}(p)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				for _, path := range []string{"/board.json", "/tree.json", "/estimate", "/ladder.json?x=0&y=0"} {
+					if w := request(h, "GET", path, nil); w.Code >= 500 {
+						t.Errorf("%s: status %d", path, w.Code)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if b := getBoard(t, h); b == nil || b.MoveNumber != played {
+		t.Errorf("board: %+v, but %d moves succeeded", b, played)
+	}
+}
+
+func TestMoveErrors(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 5})
+	// Black and white surround (1,1) and (2,1), setting up a ko
+	moves := [][2]int{{1, 0}, {2, 0}, {0, 1}, {3, 1}, {1, 2}, {2, 2}, {2, 1}, {4, 4}}
+	for i, m := range moves {
+		if code := postMove(h, m[0], m[1], []string{"B", "W"}[i%2], i); code != 200 {
+			t.Fatalf("move %v: %d", m, code)
+		}
+	}
+	cases := []struct {
+		x, y   int
+		toMove string
+		number int
+		status int
+		code   ErrorCode
+	}{
+		{0, 0, "W", 8, http.StatusConflict, CodeNotYourTurn},
+		{0, 0, "B", 3, http.StatusConflict, CodeStalePosition},
+		{5, 0, "B", 8, http.StatusUnprocessableEntity, CodeOutOfBounds},
+		{1, 0, "B", 8, http.StatusUnprocessableEntity, CodeOccupied},
+		{0, 0, "B", 8, http.StatusOK, ""},
+		// White captures at (1,1); black may not retake at once
+		{1, 1, "W", 9, http.StatusOK, ""},
+		{2, 1, "B", 10, http.StatusUnprocessableEntity, CodeKo},
+		{-1, -1, "B", 10, http.StatusOK, ""},
+		{-1, -1, "W", 11, http.StatusOK, ""},
+		{3, 3, "B", 12, http.StatusUnprocessableEntity, CodeGameOver},
+	}
+	for _, tc := range cases {
+		w := request(h, "POST", "/move", map[string]interface{}{
+			"x": tc.x, "y": tc.y, "to_move": tc.toMove, "move_number": tc.number,
+		})
+		if w.Code != tc.status {
+			t.Fatalf("move (%d,%d): status %d, want %d: %s", tc.x, tc.y, w.Code, tc.status, w.Body)
+		}
+		if tc.status == http.StatusOK {
+			continue
+		}
+		var ue UserError
+		if err := json.NewDecoder(w.Body).Decode(&ue); err != nil {
+			t.Fatal(err)
+		}
+		if ue.Reason != tc.code || ue.Err == "" {
+			t.Errorf("move (%d,%d): %+v, want code %s", tc.x, tc.y, ue, tc.code)
+		}
+		if wantPoint := w.Code == http.StatusUnprocessableEntity; wantPoint != (ue.Point != nil) ||
+			wantPoint && *ue.Point != [2]int{tc.x, tc.y} {
+			t.Errorf("move (%d,%d): point %v", tc.x, tc.y, ue.Point)
+		}
+		if (tc.code == CodeStalePosition) != (ue.MoveNumber != nil) {
+			t.Errorf("move (%d,%d): move number %v", tc.x, tc.y, ue.MoveNumber)
+		}
+	}
+}
+
+func TestSelfCaptureError(t *testing.T) {
+	h := newTestServer(t, &Config{Size: 5})
+	moves := [][2]int{{1, 0}, {4, 4}, {0, 1}}
+	for i, m := range moves {
+		if code := postMove(h, m[0], m[1], []string{"B", "W"}[i%2], i); code != 200 {
+			t.Fatalf("move %v: %d", m, code)
+		}
+	}
+	w := request(h, "POST", "/move", map[string]interface{}{"x": 0, "y": 0, "to_move": "W"})
+	var ue UserError
+	json.NewDecoder(w.Body).Decode(&ue)
+	if w.Code != http.StatusUnprocessableEntity || ue.Reason != CodeSelfCapture {
+		t.Errorf("status %d, %+v", w.Code, ue)
+	}
+}
+
+// fakeClock is a settable time source for Config.Now
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestTimedGame(t *testing.T) {
+	fake := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	config := func() *Config {
+		return &Config{
+			Size:  9,
+			Clock: &clock.Control{Kind: clock.Absolute, Main: time.Minute},
+			Now:   fake.Now,
+			Store: store,
+		}
+	}
+	h := newTestServer(t, config())
+	postMove(h, 4, 4, "B", 0)
+	fake.Advance(20 * time.Second)
+	postMove(h, 3, 3, "W", 1)
+	fake.Advance(30 * time.Second)
+	postMove(h, 5, 5, "B", 2)
+
+	var b boardJSON
+	w := request(h, "GET", "/board.json", nil)
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Clock == nil || b.Clock.Running != "W" ||
+		b.Clock.Players["B"].Main != 30 || b.Clock.Players["W"].Main != 40 {
+		t.Fatalf("clock: %+v", b.Clock)
+	}
+	tree := fmt.Sprint(decodeBoard(t, h, "/tree.json"))
+	if !strings.Contains(tree, "[TM 60]") || !strings.Contains(tree, "[WL 40]") {
+		t.Errorf("tree: %v", tree)
+	}
+
+	// The clocks survive a restart
+	want := decodeBoard(t, h, "/board.json")["clock"]
+	h = newTestServer(t, config())
+	if got := decodeBoard(t, h, "/board.json")["clock"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered clock: %v, want %v", got, want)
+	}
+
+	// White runs out of time, and loses as soon as it does
+	fake.Advance(40 * time.Second)
+	w = request(h, "GET", "/board.json", nil)
+	b = boardJSON{}
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !b.GameOver || b.Result != "B+T" || !b.Clock.Players["W"].Flagged {
+		t.Errorf("after flagging: over=%v result=%q clock=%+v", b.GameOver, b.Result, b.Clock)
+	}
+	if code := postMove(h, 6, 6, "W", 3); code != http.StatusUnprocessableEntity {
+		t.Errorf("move after flagging: %d", code)
+	}
+	if sg, _, _ := store.Current(); sg.Events[len(sg.Events)-1].Kind != EventTimeout {
+		t.Errorf("timeout not recorded: %+v", sg.Events)
+	}
+
+	// The loss cannot be taken back
+	if w := request(h, "POST", "/undo", map[string]int{}); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("undo after flagging: %d", w.Code)
+	}
+	b = boardJSON{}
+	w = request(h, "GET", "/board.json", nil)
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !b.GameOver || b.Result != "B+T" || b.MoveNumber != 3 {
+		t.Errorf("after undo: over=%v result=%q moves=%d", b.GameOver, b.Result, b.MoveNumber)
+	}
+}
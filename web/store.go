@@ -0,0 +1,203 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"nelhage.com/minigo/clock"
+	"nelhage.com/minigo/game"
+	"nelhage.com/minigo/sgf"
+)
+
+// EventKind identifies a change to a stored game
+type EventKind string
+
+const (
+	// EventMove plays a move, or a pass at -1,-1
+	EventMove EventKind = "move"
+	// EventResign resigns for Color
+	EventResign EventKind = "resign"
+	// EventUndo takes back the last move or resignation
+	EventUndo EventKind = "undo"
+	// EventJoin claims Color's seat
+	EventJoin EventKind = "join"
+	// EventTimeout ends the game because Color ran out of time
+	EventTimeout EventKind = "timeout"
+)
+
+// Event is a change to a game, as recorded in a Store
+type Event struct {
+	Kind  EventKind `json:"kind"`
+	X     int       `json:"x,omitempty"`
+	Y     int       `json:"y,omitempty"`
+	Color string    `json:"color,omitempty"`
+	// At is when the event happened, which drives the game's
+	// clocks
+	At time.Time `json:"at"`
+}
+
+// StoredGame is a game as recorded in a Store: its starting position,
+// and the events since
+type StoredGame struct {
+	ID     string
+	Start  *sgf.GameTree
+	Events []Event
+}
+
+// Store records games as they are played, so that the game in
+// progress can be recovered when the server restarts
+type Store interface {
+	// Create begins recording a new game, starting from the
+	// position at the end of start's principal variation. It
+	// becomes the current game.
+	Create(id string, start *sgf.GameTree) error
+	// Append records an event in a game. The event must be
+	// stored durably by the time Append returns.
+	Append(id string, e Event) error
+	// Current returns the current game. ok is false if no game
+	// has been created.
+	Current() (g *StoredGame, ok bool, err error)
+}
+
+var (
+	errNothingToUndo = errors.New("there is nothing to take back")
+	errOutOfTime     = errors.New("out of time")
+	errLostOnTime    = errors.New("a loss on time cannot be taken back")
+)
+
+func parseColor(s string) (game.Color, bool) {
+	switch s {
+	case "B":
+		return game.Black, true
+	case "W":
+		return game.White, true
+	}
+	return game.Black, false
+}
+
+// apply makes the change an event records to a game, its seats and,
+// if the game is timed, its clock
+func (e *Event) apply(g *game.Game, seats map[game.Color]bool, clk *clock.Clock) error {
+	switch e.Kind {
+	case EventMove:
+		c := g.ToPlay()
+		if clk != nil && !g.GameOver() && clk.State(c, e.At).Flagged {
+			return errOutOfTime
+		}
+		if err := g.Move(e.X, e.Y); err != nil {
+			return err
+		}
+		if clk != nil {
+			clk.Move(c, e.At)
+			g.SetTimeLeft(clk.Control.TimeLeft(clk.State(c, e.At)))
+		}
+		return nil
+	case EventUndo:
+		if _, ok := g.TimedOut(); ok {
+			return errLostOnTime
+		}
+		if !g.Undo() {
+			return errNothingToUndo
+		}
+		if clk != nil && g.MoveNumber() > 0 && !g.GameOver() {
+			clk.Start(g.ToPlay(), e.At)
+		} else if clk != nil {
+			clk.Stop(e.At)
+		}
+		return nil
+	}
+	c, ok := parseColor(e.Color)
+	if !ok {
+		return fmt.Errorf("%s event: bad color %q", e.Kind, e.Color)
+	}
+	switch e.Kind {
+	case EventResign, EventTimeout:
+		end := g.Resign
+		if e.Kind == EventTimeout {
+			end = g.TimeOut
+		}
+		if err := end(c); err != nil {
+			return err
+		}
+		if clk != nil {
+			clk.Stop(e.At)
+		}
+		return nil
+	case EventJoin:
+		seats[c] = true
+		return nil
+	}
+	return fmt.Errorf("unknown event %q", e.Kind)
+}
+
+// replay reconstructs a stored game, its seats and, if ctrl is not
+// nil, its clock
+func (sg *StoredGame) replay(ctrl *clock.Control) (*game.Game, map[game.Color]bool, *clock.Clock, error) {
+	g, err := game.FromSGF(sg.Start)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("game %s: %v", sg.ID, err)
+	}
+	seats := make(map[game.Color]bool)
+	var clk *clock.Clock
+	if ctrl != nil {
+		clk = clock.New(*ctrl)
+	}
+	for i := range sg.Events {
+		if err := sg.Events[i].apply(g, seats, clk); err != nil {
+			return nil, nil, nil, fmt.Errorf("game %s: event %d: %v", sg.ID, i, err)
+		}
+	}
+	return g, seats, clk, nil
+}
+
+// MemoryStore is a Store that keeps games in memory, for tests and
+// servers whose games need not outlive them
+type MemoryStore struct {
+	mu      sync.Mutex
+	games   map[string]*StoredGame
+	current string
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{games: make(map[string]*StoredGame)}
+}
+
+// Create implements Store
+func (m *MemoryStore) Create(id string, start *sgf.GameTree) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.games[id]; ok {
+		return fmt.Errorf("game %s already exists", id)
+	}
+	m.games[id] = &StoredGame{ID: id, Start: start}
+	m.current = id
+	return nil
+}
+
+// Append implements Store
+func (m *MemoryStore) Append(id string, e Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[id]
+	if !ok {
+		return fmt.Errorf("no game %s", id)
+	}
+	g.Events = append(g.Events, e)
+	return nil
+}
+
+// Current implements Store
+func (m *MemoryStore) Current() (*StoredGame, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[m.current]
+	if !ok {
+		return nil, false, nil
+	}
+	out := *g
+	out.Events = append([]Event(nil), g.Events...)
+	return &out, true, nil
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nelhage.com/minigo/game"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := f.Current(); ok || err != nil {
+		t.Fatalf("empty store: ok=%v err=%v", ok, err)
+	}
+	start := game.New(9).SGF()
+	if err := f.Create("abc", start); err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{
+		{Kind: EventJoin, Color: "B"},
+		{Kind: EventMove, X: 4, Y: 4},
+		{Kind: EventMove, X: -1, Y: -1},
+		{Kind: EventUndo},
+	}
+	for _, e := range events {
+		if err := f.Append("abc", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Simulate a crash partway through an append
+	out, err := os.OpenFile(filepath.Join(dir, "abc.log"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Write([]byte(`{"kind":"mo`))
+	out.Close()
+
+	f, _ = NewFileStore(dir)
+	g, ok, err := f.Current()
+	if !ok || err != nil {
+		t.Fatalf("Current: ok=%v err=%v", ok, err)
+	}
+	if g.ID != "abc" || !reflect.DeepEqual(g.Events, events) {
+		t.Errorf("recovered %+v", g)
+	}
+	if !reflect.DeepEqual(g.Start, start) {
+		t.Errorf("start: %+v", g.Start)
+	}
+
+	if err := f.Append("abc", Event{Kind: EventMove, X: 2, Y: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if g, _, err := f.Current(); err != nil || len(g.Events) != len(events)+1 {
+		t.Errorf("after torn write: %+v, %v", g, err)
+	}
+
+	if err := f.Create("abc", start); err == nil {
+		t.Error("created a game twice")
+	}
+}
+
+func testRecovery(t *testing.T, c func() *Config) {
+	h := newTestServer(t, c())
+	black := join(t, h, "B")
+	white := join(t, h, "W")
+	for i, m := range [][2]int{{4, 4}, {3, 3}, {5, 5}} {
+		token := []string{black, white}[i%2]
+		if code := move(h, token, m[0], m[1], []string{"B", "W"}[i%2]); code != 200 {
+			t.Fatalf("move %v: %d", m, code)
+		}
+	}
+	requestAs(h, black, "POST", "/undo", nil)
+	want := decodeBoard(t, h, "/board.json")
+
+	// A restarted server resumes the game, and honors the
+	// players' tokens
+	h = newTestServer(t, c())
+	if got := decodeBoard(t, h, "/board.json"); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered:\n%v\nwant:\n%v", got, want)
+	}
+	if code := move(h, black, 6, 6, "B"); code != 200 {
+		t.Errorf("move after restart: %d", code)
+	}
+
+	// Once the game is over, a restart begins a new one
+	requestAs(h, white, "POST", "/resign", nil)
+	h = newTestServer(t, c())
+	if b := getBoard(t, h); b.MoveNumber != 0 {
+		t.Errorf("after a finished game: %+v", b)
+	}
+	if code := move(h, black, 4, 4, "B"); code != 401 {
+		t.Errorf("old token in new game: %d", code)
+	}
+}
+
+func TestRecoveryMemory(t *testing.T) {
+	store := NewMemoryStore()
+	testRecovery(t, func() *Config {
+		return &Config{Size: 9, Secret: "sekrit", Store: store}
+	})
+}
+
+func TestRecoveryFile(t *testing.T) {
+	dir := t.TempDir()
+	testRecovery(t, func() *Config {
+		return &Config{Size: 9, Secret: "sekrit", Data: dir}
+	})
+}
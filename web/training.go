@@ -0,0 +1,149 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"nelhage.com/minigo/sgf"
+	"nelhage.com/minigo/tsumego"
+)
+
+// training holds the state of the server's problem-solving mode
+type training struct {
+	problems *sgf.Collection
+	// index is the problem being attempted
+	index   int
+	attempt *tsumego.Attempt
+	// reply is the problem's answer to the last move, if any
+	reply        *tsumego.Move
+	progress     *tsumego.Progress
+	progressPath string
+}
+
+type problemJSON struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	State string `json:"state"`
+	// Reply is the problem's answer to the last move
+	Reply *[2]int `json:"reply,omitempty"`
+}
+
+func newTraining(path, progressPath string) (*training, error) {
+	_, c, err := loadSGF(path)
+	if err != nil {
+		return nil, err
+	}
+	if progressPath == "" {
+		progressPath = path + ".progress.json"
+	}
+	p, err := tsumego.LoadProgress(progressPath)
+	if err != nil {
+		return nil, err
+	}
+	t := &training{problems: c, progress: p, progressPath: progressPath}
+	if err := t.start(0); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// start begins a new attempt at problem i
+func (t *training) start(i int) error {
+	if i < 0 || i >= len(t.problems.Trees) {
+		return badRequest("no problem %d", i)
+	}
+	a, err := tsumego.NewAttempt(t.problems.Trees[i])
+	if err != nil {
+		return fmt.Errorf("problem %d: %v", i, err)
+	}
+	t.index, t.attempt, t.reply = i, a, nil
+	return nil
+}
+
+func (t *training) name(i int) string {
+	if v, ok := t.problems.Trees[i].Principal.Nodes[0].Value("GN"); ok {
+		return string(v)
+	}
+	return fmt.Sprintf("Problem %d", i+1)
+}
+
+// id returns the key of problem i's progress, which stays the same if
+// the collection is edited
+func (t *training) id(i int) string {
+	return tsumego.ProblemID(t.problems.Trees[i])
+}
+
+// play makes the player's move, and records the result if it ends the
+// attempt
+func (t *training) play(x, y int) error {
+	reply, err := t.attempt.Play(x, y)
+	if err == tsumego.ErrFinished {
+		return &UserError{Err: "the problem is finished", Reason: CodeProblemFinished, Point: &[2]int{x, y}}
+	}
+	if err != nil {
+		return moveError(err, x, y)
+	}
+	t.reply = reply
+	if t.attempt.State != tsumego.Playing {
+		t.progress.Record(t.id(t.index), t.attempt.State)
+		if err := t.progress.Save(t.progressPath); err != nil {
+			log.Printf("saving progress: %v", err)
+		}
+	}
+	return nil
+}
+
+func (t *training) problemJSON() *problemJSON {
+	out := &problemJSON{
+		Index: t.index,
+		Name:  t.name(t.index),
+		Color: colorStr(t.attempt.Color),
+		State: t.attempt.State.String(),
+	}
+	if t.reply != nil {
+		out.Reply = &[2]int{t.reply.X, t.reply.Y}
+	}
+	return out
+}
+
+// serveProblems lists the loaded problems and the progress on each
+func (s *Server) serveProblems(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.training == nil {
+		return nil, badRequest("no problems were loaded")
+	}
+	t := s.training
+	type problem struct {
+		Index int    `json:"index"`
+		Name  string `json:"name"`
+		tsumego.Result
+	}
+	out := struct {
+		Current  int       `json:"current"`
+		Problems []problem `json:"problems"`
+	}{Current: t.index}
+	for i := range t.problems.Trees {
+		out.Problems = append(out.Problems, problem{i, t.name(i), t.progress.Get(t.id(i))})
+	}
+	return &out, nil
+}
+
+// handleProblem starts, or restarts, the problem with a given index
+func (s *Server) handleProblem(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if s.training == nil {
+		return nil, badRequest("no problems were loaded")
+	}
+	var args struct {
+		Index int `json:"index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if err := s.training.start(args.Index); err != nil {
+		return nil, err
+	}
+	s.game = s.training.attempt.Game
+	return s.serveBoard(w, r)
+}